@@ -29,6 +29,8 @@ type Cache struct {
 	cacheMutex   sync.RWMutex
 	cache        *lru.Cache
 	compiler     *compiler
+	features     Features
+	options      Options
 }
 
 // NewCache creates a cache. The maximum number of entries determines
@@ -36,19 +38,38 @@ type Cache struct {
 // entry.
 //
 // The features are used to get a suitable compiler.
+//
+// Cost estimation is disabled for all expressions compiled by the cache.
 func NewCache(maxCacheEntries int, features Features) *Cache {
+	return NewCacheWithOptions(maxCacheEntries, features, Options{DisableCostEstimation: true})
+}
+
+// NewCacheWithOptions is like [NewCache], except that the caller determines
+// how expressions get compiled. When cost estimation is enabled in the
+// options, then [CompilationResult.MaxCost] of cached results contains the
+// estimated worst-case cost.
+func NewCacheWithOptions(maxCacheEntries int, features Features, options Options) *Cache {
 	return &Cache{
 		compileMutex: keymutex.NewHashed(0),
 		cache:        lru.New(maxCacheEntries),
 		compiler:     GetCompiler(features),
+		features:     features,
+		options:      options,
 	}
 }
 
+// Features returns the features that were passed to [NewCache] or
+// [NewCacheWithOptions]. Compilation results from caches with different
+// features are not interchangeable.
+func (c *Cache) Features() Features {
+	return c.features
+}
+
 // GetOrCompile checks whether the cache already has a compilation result
 // and returns that if available. Otherwise it compiles, stores successful
 // results and returns the new result.
 //
-// Cost estimation is disabled.
+// The options passed to [NewCacheWithOptions] are used for compilation.
 func (c *Cache) GetOrCompile(expression string) CompilationResult {
 	// Compiling a CEL expression is expensive enough that it is cheaper
 	// to lock a mutex than doing it several times in parallel.
@@ -61,7 +82,7 @@ func (c *Cache) GetOrCompile(expression string) CompilationResult {
 		return *cached
 	}
 
-	expr := c.compiler.CompileCELExpression(expression, Options{DisableCostEstimation: true})
+	expr := c.compiler.CompileCELExpression(expression, c.options)
 	if expr.Error == nil {
 		c.add(expression, &expr)
 	}
@@ -96,7 +117,7 @@ func (c *Cache) Check(expression string) CompilationResult {
 		return *cached
 	}
 
-	expr := c.compiler.CompileCELExpression(expression, Options{DisableCostEstimation: true})
+	expr := c.compiler.CompileCELExpression(expression, c.options)
 	if expr.Error == nil {
 		c.add(expression, &expr)
 	}
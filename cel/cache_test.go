@@ -102,3 +102,16 @@ func TestCacheConcurrency(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestCacheWithCostEstimation(t *testing.T) {
+	cache := NewCacheWithOptions(2, Features{}, Options{})
+
+	result := cache.GetOrCompile(`device.driver == "dra.example.com"`)
+	require.Nil(t, result.Error)
+	assert.Equal(t, uint64(4), result.MaxCost, "estimated cost")
+
+	resultAgain := cache.GetOrCompile(`device.driver == "dra.example.com"`)
+	if result != resultAgain {
+		t.Fatal("result with cost estimate should have been cached")
+	}
+}
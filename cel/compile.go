@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"slices"
 	"strings"
 	"sync"
 
@@ -49,6 +50,17 @@ const (
 	multiAllocVar = "allowMultipleAllocations"
 	attributesVar = "attributes"
 	capacityVar   = "capacity"
+
+	taintsVar            = "taints"
+	taintKeyVar          = "key"
+	taintValueVar        = "value"
+	taintEffectVar       = "effect"
+	bindingConditionsVar = "bindingConditions"
+	consumesCountersVar  = "consumesCounters"
+
+	// maxConsumesCountersPerDevice is an upper bound for the number of
+	// counter sets consumed by a device, see ResourceSlice validation.
+	maxConsumesCountersPerDevice = 32
 )
 
 var (
@@ -69,12 +81,23 @@ var (
 	// Same for capacity.
 	innerCapacityMapType = apiservercel.NewMapType(idType, apiservercel.QuantityDeclType, resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice)
 	outerCapacityMapType = apiservercel.NewMapType(domainType, innerCapacityMapType, resourceapi.ResourceSliceMaxAttributesAndCapacitiesPerDevice)
+
+	// Taint keys are label names (optional DNS subdomain prefix, slash, name),
+	// values are label values. Binding conditions are condition types,
+	// which have the same format as label names.
+	labelNameType   = withMaxElements(apiservercel.StringType, 253+1+63)
+	labelValueType  = withMaxElements(apiservercel.StringType, 63)
+	taintEffectType = withMaxElements(apiservercel.StringType, 16)
+	counterSetType  = withMaxElements(apiservercel.StringType, resourceapi.DeviceMaxIDLength)
 )
 
 // Features contains feature gates supported by the package.
 type Features struct {
 	EnableConsumableCapacity bool
 	EnableListTypeAttributes bool
+	// EnableDeviceSchedulingFields makes device.taints, device.bindingConditions
+	// and device.consumesCounters available in expressions.
+	EnableDeviceSchedulingFields bool
 }
 
 func GetCompiler(features Features) *compiler {
@@ -116,6 +139,16 @@ type Device struct {
 	AllowMultipleAllocations *bool
 	Attributes               map[resourceapi.QualifiedName]resourceapi.DeviceAttribute
 	Capacity                 map[resourceapi.QualifiedName]resourceapi.DeviceCapacity
+
+	// Taints, BindingConditions and ConsumesCounters are exposed as
+	// device.taints (list of objects with key, value and effect),
+	// device.bindingConditions (list of strings) and
+	// device.consumesCounters (list of counter set names).
+	// Expressions can only use them if
+	// [Features.EnableDeviceSchedulingFields] is set.
+	Taints            []resourceapi.DeviceTaint
+	BindingConditions []string
+	ConsumesCounters  []resourceapi.DeviceCounterConsumption
 }
 
 type compiler struct {
@@ -348,12 +381,32 @@ func (c CompilationResult) DeviceMatches(ctx context.Context, input Device) (boo
 		capacity[domain].(map[string]apiservercel.Quantity)[id] = apiservercel.Quantity{Quantity: &cap.Value}
 	}
 
+	taints := make([]any, 0, len(input.Taints))
+	for _, taint := range input.Taints {
+		taints = append(taints, map[string]any{
+			taintKeyVar:    taint.Key,
+			taintValueVar:  taint.Value,
+			taintEffectVar: string(taint.Effect),
+		})
+	}
+	bindingConditions := input.BindingConditions
+	if bindingConditions == nil {
+		bindingConditions = []string{}
+	}
+	consumesCounters := make([]string, 0, len(input.ConsumesCounters))
+	for _, consumption := range input.ConsumesCounters {
+		consumesCounters = append(consumesCounters, consumption.CounterSet)
+	}
+
 	variables := map[string]any{
 		deviceVar: map[string]any{
-			driverVar:     input.Driver,
-			multiAllocVar: ptr.Deref(input.AllowMultipleAllocations, false),
-			attributesVar: newStringInterfaceMapWithDefault(c.Environment.CELTypeAdapter(), attributes, c.emptyMapVal),
-			capacityVar:   newStringInterfaceMapWithDefault(c.Environment.CELTypeAdapter(), capacity, c.emptyMapVal),
+			driverVar:            input.Driver,
+			multiAllocVar:        ptr.Deref(input.AllowMultipleAllocations, false),
+			attributesVar:        newStringInterfaceMapWithDefault(c.Environment.CELTypeAdapter(), attributes, c.emptyMapVal),
+			capacityVar:          newStringInterfaceMapWithDefault(c.Environment.CELTypeAdapter(), capacity, c.emptyMapVal),
+			taintsVar:            taints,
+			bindingConditionsVar: bindingConditions,
+			consumesCountersVar:  consumesCounters,
 		},
 	}
 
@@ -434,6 +487,26 @@ func newCompiler(features Features) *compiler {
 	fieldsV136ConsumableCapacityListTypeAttributes = append(fieldsV136ConsumableCapacityListTypeAttributes, fieldsV136ListTypeAttributes...)
 	deviceTypeV136ConsumableCapacityListTypeAttributes := apiservercel.NewObjectType("kubernetes.DRADevice", fields(fieldsV136ConsumableCapacityListTypeAttributes...))
 
+	// Additional fields, feature-gated below. They can be combined with
+	// each of the previous device types.
+	taintType := apiservercel.NewObjectType("kubernetes.DRADeviceTaint", fields(
+		field(taintKeyVar, labelNameType, true),
+		field(taintValueVar, labelValueType, true),
+		field(taintEffectVar, taintEffectType, true),
+	))
+	fieldsSchedulingFields := []*apiservercel.DeclField{
+		field(taintsVar, apiservercel.NewListType(taintType, resourceapi.DeviceTaintsMaxLength), true),
+		field(bindingConditionsVar, apiservercel.NewListType(labelNameType, resourceapi.BindingConditionsMaxSize), true),
+		field(consumesCountersVar, apiservercel.NewListType(counterSetType, maxConsumesCountersPerDevice), true),
+	}
+	withSchedulingFields := func(base []*apiservercel.DeclField) *apiservercel.DeclType {
+		return apiservercel.NewObjectType("kubernetes.DRADevice", fields(slices.Concat(base, fieldsSchedulingFields)...))
+	}
+	deviceTypeSchedulingFields := withSchedulingFields(fieldsV131)
+	deviceTypeConsumableCapacitySchedulingFields := withSchedulingFields(fieldsV134ConsumableCapacity)
+	deviceTypeListTypeAttributesSchedulingFields := withSchedulingFields(fieldsV136ListTypeAttributes)
+	deviceTypeConsumableCapacityListTypeAttributesSchedulingFields := withSchedulingFields(fieldsV136ConsumableCapacityListTypeAttributes)
+
 	versioned := []environment.VersionedOptions{
 		{
 			IntroducedVersion: version.MajorMinor(1, 31),
@@ -453,7 +526,7 @@ func newCompiler(features Features) *compiler {
 		{
 			IntroducedVersion: version.MajorMinor(1, 31),
 			FeatureEnabled: func() bool {
-				return !features.EnableConsumableCapacity && !features.EnableListTypeAttributes
+				return !features.EnableConsumableCapacity && !features.EnableListTypeAttributes && !features.EnableDeviceSchedulingFields
 			},
 			EnvOptions: []cel.EnvOption{
 				cel.Variable(deviceVar, deviceTypeV131.CelType()),
@@ -465,7 +538,7 @@ func newCompiler(features Features) *compiler {
 		{
 			IntroducedVersion: version.MajorMinor(1, 34),
 			FeatureEnabled: func() bool {
-				return features.EnableConsumableCapacity && !features.EnableListTypeAttributes
+				return features.EnableConsumableCapacity && !features.EnableListTypeAttributes && !features.EnableDeviceSchedulingFields
 			},
 			EnvOptions: []cel.EnvOption{
 				cel.Variable(deviceVar, deviceTypeV134ConsumableCapacity.CelType()),
@@ -479,7 +552,7 @@ func newCompiler(features Features) *compiler {
 			// when ListTypeAttributes was enabled and ConsumableCapacity was disabled.
 			IntroducedVersion: version.MajorMinor(1, 36),
 			FeatureEnabled: func() bool {
-				return !features.EnableConsumableCapacity && features.EnableListTypeAttributes
+				return !features.EnableConsumableCapacity && features.EnableListTypeAttributes && !features.EnableDeviceSchedulingFields
 			},
 			EnvOptions: []cel.EnvOption{
 				cel.Variable(deviceVar, deviceTypeV136ListTypeAttributes.CelType()),
@@ -493,7 +566,7 @@ func newCompiler(features Features) *compiler {
 			// when both ListTypeAttributes and ConsumableCapacity was enabled.
 			IntroducedVersion: version.MajorMinor(1, 36),
 			FeatureEnabled: func() bool {
-				return features.EnableConsumableCapacity && features.EnableListTypeAttributes
+				return features.EnableConsumableCapacity && features.EnableListTypeAttributes && !features.EnableDeviceSchedulingFields
 			},
 			EnvOptions: []cel.EnvOption{
 				cel.Variable(deviceVar, deviceTypeV136ConsumableCapacityListTypeAttributes.CelType()),
@@ -518,6 +591,44 @@ func newCompiler(features Features) *compiler {
 			},
 		},
 	}
+	// The same combinations again, this time with the scheduling fields.
+	// The type with all fields must be last. FeatureEnabled determines
+	// whether they are available, so they use the same version as the
+	// types above.
+	for _, variant := range []struct {
+		featureEnabled func() bool
+		deviceType     *apiservercel.DeclType
+	}{
+		{
+			featureEnabled: func() bool { return !features.EnableConsumableCapacity && !features.EnableListTypeAttributes },
+			deviceType:     deviceTypeSchedulingFields,
+		},
+		{
+			featureEnabled: func() bool { return features.EnableConsumableCapacity && !features.EnableListTypeAttributes },
+			deviceType:     deviceTypeConsumableCapacitySchedulingFields,
+		},
+		{
+			featureEnabled: func() bool { return !features.EnableConsumableCapacity && features.EnableListTypeAttributes },
+			deviceType:     deviceTypeListTypeAttributesSchedulingFields,
+		},
+		{
+			featureEnabled: func() bool { return features.EnableConsumableCapacity && features.EnableListTypeAttributes },
+			deviceType:     deviceTypeConsumableCapacityListTypeAttributesSchedulingFields,
+		},
+	} {
+		versioned = append(versioned, environment.VersionedOptions{
+			IntroducedVersion: version.MajorMinor(1, 36),
+			FeatureEnabled: func() bool {
+				return features.EnableDeviceSchedulingFields && variant.featureEnabled()
+			},
+			EnvOptions: []cel.EnvOption{
+				cel.Variable(deviceVar, variant.deviceType.CelType()),
+			},
+			DeclTypes: []*apiservercel.DeclType{
+				variant.deviceType,
+			},
+		})
+	}
 	envset, err := envset.Extend(versioned...)
 	if err != nil {
 		panic(fmt.Errorf("internal error building CEL environment: %w", err))
@@ -525,7 +636,7 @@ func newCompiler(features Features) *compiler {
 	// return with newest deviceType
 	return &compiler{
 		envset:        envset,
-		deviceType:    deviceTypeV136ConsumableCapacityListTypeAttributes,
+		deviceType:    deviceTypeConsumableCapacityListTypeAttributesSchedulingFields,
 		features:      features,
 		attributeType: attributeTypeV136ListTypeAttributes,
 	}
@@ -699,6 +699,8 @@ func TestCELFeatureCombinations(t *testing.T) {
 		includesFunc bool
 		// multipleAllocations covers fields introduced by DRAConsumableCapacity.
 		multipleAllocations bool
+		// schedulingFields covers taints, bindingConditions and consumesCounters.
+		schedulingFields bool
 	}
 
 	type expressionTest struct {
@@ -714,6 +716,7 @@ func TestCELFeatureCombinations(t *testing.T) {
 		listTypeAttributes:  true,
 		includesFunc:        true,
 		multipleAllocations: true,
+		schedulingFields:    true,
 	}
 
 	tests := map[string]struct {
@@ -773,6 +776,25 @@ func TestCELFeatureCombinations(t *testing.T) {
 				multipleAllocations: true,
 			},
 		},
+		"new-expressions-scheduling-fields": {
+			envType:  environment.NewExpressions,
+			features: Features{EnableDeviceSchedulingFields: true},
+			expectsCompile: compileExpectations{
+				scalarAttribute:  true,
+				schedulingFields: true,
+			},
+		},
+		"new-expressions-all-features-and-scheduling-fields": {
+			envType:  environment.NewExpressions,
+			features: Features{EnableConsumableCapacity: true, EnableListTypeAttributes: true, EnableDeviceSchedulingFields: true},
+			expectsCompile: compileExpectations{
+				scalarAttribute:     true,
+				listTypeAttributes:  true,
+				includesFunc:        true,
+				multipleAllocations: true,
+				schedulingFields:    true,
+			},
+		},
 	}
 
 	expressions := func(expectsCompile compileExpectations) map[string]expressionTest {
@@ -827,6 +849,32 @@ func TestCELFeatureCombinations(t *testing.T) {
 				},
 				expectsCompile: expectsCompile.listTypeAttributes && expectsCompile.multipleAllocations,
 			},
+			"scheduling-fields": {
+				expression: `device.taints.all(t, t.effect != "NoExecute") && device.bindingConditions.size() == 0 && "memory" in device.consumesCounters`,
+				device: Device{
+					Driver:     "dra.example.com",
+					Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{},
+					Taints: []resourceapi.DeviceTaint{
+						{Key: "example.com/unhealthy", Effect: resourceapi.DeviceTaintEffectNoSchedule},
+					},
+					ConsumesCounters: []resourceapi.DeviceCounterConsumption{
+						{CounterSet: "memory"},
+					},
+				},
+				expectsCompile: expectsCompile.schedulingFields,
+			},
+			"scheduling-fields-with-multiple-allocations": {
+				expression: `device.allowMultipleAllocations && device.taints.exists(t, t.key == "example.com/unhealthy" && t.value == "true")`,
+				device: Device{
+					Driver:                   "dra.example.com",
+					AllowMultipleAllocations: new(true),
+					Attributes:               map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{},
+					Taints: []resourceapi.DeviceTaint{
+						{Key: "example.com/unhealthy", Value: "true", Effect: resourceapi.DeviceTaintEffectNoSchedule},
+					},
+				},
+				expectsCompile: expectsCompile.schedulingFields && expectsCompile.multipleAllocations,
+			},
 		}
 	}
 
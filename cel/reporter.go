@@ -0,0 +1,179 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cel
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+// RuntimeErrorKind is a coarse classification of CEL runtime errors.
+// It is meant to be used as label in metrics and as reason in Events,
+// so the set of values is small and fixed.
+type RuntimeErrorKind string
+
+const (
+	RuntimeErrorNoSuchKey         RuntimeErrorKind = "NoSuchKey"
+	RuntimeErrorCostLimitExceeded RuntimeErrorKind = "CostLimitExceeded"
+	RuntimeErrorInterrupted       RuntimeErrorKind = "Interrupted"
+	RuntimeErrorResultType        RuntimeErrorKind = "ResultType"
+	RuntimeErrorOther             RuntimeErrorKind = "Other"
+)
+
+// ClassifyRuntimeError determines the kind of an error returned by
+// [CompilationResult.DeviceMatches]. CEL does not have typed errors,
+// so this has to rely on the error strings.
+func ClassifyRuntimeError(err error) RuntimeErrorKind {
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return RuntimeErrorInterrupted
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "no such key:"):
+		return RuntimeErrorNoSuchKey
+	case strings.Contains(msg, "cost limit exceeded"):
+		return RuntimeErrorCostLimitExceeded
+	case strings.Contains(msg, "operation interrupted"):
+		return RuntimeErrorInterrupted
+	case strings.Contains(msg, "could not be converted to bool"):
+		return RuntimeErrorResultType
+	default:
+		return RuntimeErrorOther
+	}
+}
+
+// RuntimeErrorReporter aggregates CEL runtime errors by expression and
+// [RuntimeErrorKind]. A broken selector which gets evaluated for each
+// device fails the same way many times; the reporter ensures that
+// such errors get reported only once per interval.
+//
+// It is safe for concurrent use and can be shared between different
+// components.
+type RuntimeErrorReporter struct {
+	interval time.Duration
+	clock    clock.PassiveClock
+
+	mutex   sync.Mutex
+	entries map[runtimeErrorKey]*runtimeErrorEntry
+}
+
+type runtimeErrorKey struct {
+	expression string
+	kind       RuntimeErrorKind
+}
+
+type runtimeErrorEntry struct {
+	total        int64
+	suppressed   int64
+	lastReported time.Time
+	lastError    error
+}
+
+// RuntimeErrorCount contains the aggregated information about one
+// expression and kind of error.
+type RuntimeErrorCount struct {
+	Expression string
+	Kind       RuntimeErrorKind
+	// Total is the number of errors recorded since the reporter was created.
+	Total int64
+	// LastError is the most recent error.
+	LastError error
+}
+
+// NewRuntimeErrorReporter creates a reporter which lets at most one error
+// per expression and kind through per interval.
+func NewRuntimeErrorReporter(interval time.Duration) *RuntimeErrorReporter {
+	return newRuntimeErrorReporter(interval, clock.RealClock{})
+}
+
+func newRuntimeErrorReporter(interval time.Duration, clock clock.PassiveClock) *RuntimeErrorReporter {
+	return &RuntimeErrorReporter{
+		interval: interval,
+		clock:    clock,
+		entries:  make(map[runtimeErrorKey]*runtimeErrorEntry),
+	}
+}
+
+// Record counts the error. It returns true if the caller should report
+// the error now, for example by logging it or emitting an Event. In that
+// case, suppressed is the number of identical errors which were not
+// reported since the previous time.
+func (r *RuntimeErrorReporter) Record(expression string, err error) (kind RuntimeErrorKind, report bool, suppressed int64) {
+	kind = ClassifyRuntimeError(err)
+	key := runtimeErrorKey{expression: expression, kind: kind}
+	now := r.clock.Now()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entry := r.entries[key]
+	if entry == nil {
+		entry = &runtimeErrorEntry{}
+		r.entries[key] = entry
+	}
+	entry.total++
+	entry.lastError = err
+	if !entry.lastReported.IsZero() && now.Sub(entry.lastReported) < r.interval {
+		entry.suppressed++
+		return kind, false, 0
+	}
+	suppressed = entry.suppressed
+	entry.suppressed = 0
+	entry.lastReported = now
+	return kind, true, suppressed
+}
+
+// Counts returns the aggregated errors, sorted by expression and kind.
+func (r *RuntimeErrorReporter) Counts() []RuntimeErrorCount {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	counts := make([]RuntimeErrorCount, 0, len(r.entries))
+	for key, entry := range r.entries {
+		counts = append(counts, RuntimeErrorCount{
+			Expression: key.expression,
+			Kind:       key.kind,
+			Total:      entry.total,
+			LastError:  entry.lastError,
+		})
+	}
+	slices.SortFunc(counts, func(a, b RuntimeErrorCount) int {
+		return cmp.Or(cmp.Compare(a.Expression, b.Expression), cmp.Compare(a.Kind, b.Kind))
+	})
+	return counts
+}
+
+// Forget drops all information about the expression, for example
+// after the object which contained it was deleted.
+func (r *RuntimeErrorReporter) Forget(expression string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for key := range r.entries {
+		if key.expression == expression {
+			delete(r.entries, key)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestClassifyRuntimeError(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err    error
+		expect RuntimeErrorKind
+	}{
+		"no-such-key": {
+			err:    errors.New("no such key: noSuchAttr"),
+			expect: RuntimeErrorNoSuchKey,
+		},
+		"enhanced-no-such-key": {
+			err:    EnhanceRuntimeError(errors.New("no such key: noSuchAttr")),
+			expect: RuntimeErrorNoSuchKey,
+		},
+		"cost-limit": {
+			err:    errors.New("operation cancelled: actual cost limit exceeded"),
+			expect: RuntimeErrorCostLimitExceeded,
+		},
+		"interrupted": {
+			err:    fmt.Errorf("%w: %w", errors.New("operation interrupted"), context.Canceled),
+			expect: RuntimeErrorInterrupted,
+		},
+		"result-type": {
+			err:    errors.New("CEL result of type list could not be converted to bool: unsupported type"),
+			expect: RuntimeErrorResultType,
+		},
+		"other": {
+			err:    errors.New("division by zero"),
+			expect: RuntimeErrorOther,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, ClassifyRuntimeError(tc.err))
+		})
+	}
+}
+
+func TestRuntimeErrorReporter(t *testing.T) {
+	clock := testingclock.NewFakePassiveClock(time.Now())
+	reporter := newRuntimeErrorReporter(time.Minute, clock)
+	exprA := `device.attributes["a"].x`
+	exprB := `device.attributes["b"].x`
+	noSuchKey := errors.New("no such key: x")
+	other := errors.New("division by zero")
+
+	record := func(expression string, err error) (bool, int64) {
+		_, report, suppressed := reporter.Record(expression, err)
+		return report, suppressed
+	}
+
+	// The first error gets reported, identical ones don't.
+	report, suppressed := record(exprA, noSuchKey)
+	assert.True(t, report, "first error")
+	assert.Equal(t, int64(0), suppressed)
+	report, _ = record(exprA, noSuchKey)
+	assert.False(t, report, "second error")
+	report, _ = record(exprA, noSuchKey)
+	assert.False(t, report, "third error")
+
+	// Different expression or kind are tracked separately.
+	report, _ = record(exprB, noSuchKey)
+	assert.True(t, report, "other expression")
+	report, _ = record(exprA, other)
+	assert.True(t, report, "other kind")
+
+	// After the interval, the next error gets reported together
+	// with the number of suppressed errors.
+	clock.SetTime(clock.Now().Add(time.Minute))
+	report, suppressed = record(exprA, noSuchKey)
+	assert.True(t, report, "error after interval")
+	assert.Equal(t, int64(2), suppressed)
+
+	assert.Equal(t, []RuntimeErrorCount{
+		{Expression: exprA, Kind: RuntimeErrorNoSuchKey, Total: 4, LastError: noSuchKey},
+		{Expression: exprA, Kind: RuntimeErrorOther, Total: 1, LastError: other},
+		{Expression: exprB, Kind: RuntimeErrorNoSuchKey, Total: 1, LastError: noSuchKey},
+	}, reporter.Counts())
+
+	reporter.Forget(exprA)
+	assert.Equal(t, []RuntimeErrorCount{
+		{Expression: exprB, Kind: RuntimeErrorNoSuchKey, Total: 1, LastError: noSuchKey},
+	}, reporter.Counts())
+}
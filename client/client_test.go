@@ -19,6 +19,12 @@ package client
 import (
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+
+	resourcev1beta2 "k8s.io/api/resource/v1beta2"
+	"k8s.io/apimachinery/pkg/api/equality"
+	resourceapplyv1 "k8s.io/client-go/applyconfigurations/resource/v1"
+	resourceapplyv1beta2 "k8s.io/client-go/applyconfigurations/resource/v1beta2"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	restclient "k8s.io/client-go/rest"
@@ -53,3 +59,25 @@ func TestDoesClientSupportWatchListSemantics(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertApplyConfiguration(t *testing.T) {
+	in := resourceapplyv1.ResourceSlice("slice").
+		WithLabels(map[string]string{"a": "b"}).
+		WithSpec(resourceapplyv1.ResourceSliceSpec().
+			WithDriver("driver.example.com").
+			WithAllNodes(true).
+			WithDevices(resourceapplyv1.Device().WithName("dev-a")))
+	out, err := convertApplyConfiguration[resourceapplyv1.ResourceSliceApplyConfiguration, resourceapplyv1beta2.ResourceSliceApplyConfiguration](in, resourcev1beta2.SchemeGroupVersion.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := resourceapplyv1beta2.ResourceSlice("slice").
+		WithLabels(map[string]string{"a": "b"}).
+		WithSpec(resourceapplyv1beta2.ResourceSliceSpec().
+			WithDriver("driver.example.com").
+			WithAllNodes(true).
+			WithDevices(resourceapplyv1beta2.Device().WithName("dev-a")))
+	if !equality.Semantic.DeepEqual(expected, out) {
+		t.Fatalf("unexpected apply configuration:\n%s", cmp.Diff(expected, out))
+	}
+}
@@ -19,8 +19,8 @@ limitations under the License.
 // types get converted to and from the most recent API version supported by the
 // apiserver.
 //
-// Patching and server-side-apply are not supported and return the
-// [ErrNotImplemented] error. It would be necessary to convert the patch or
-// apply configuration, which is close to impossible (patch) and more code
-// (apply configuration).
+// Patching is not supported and returns the [ErrNotImplemented] error. It
+// would be necessary to convert the patch, which is close to impossible.
+// Server-side-apply is supported with the v1 and v1beta2 APIs, which have
+// the same structure. With v1beta1 it returns [ErrNotImplemented].
 package client
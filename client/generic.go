@@ -18,8 +18,11 @@ package client
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"sync"
 
+	resourcev1beta2 "k8s.io/api/resource/v1beta2"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -222,8 +225,27 @@ func (t *convertingClient[NP, N, NL, NAC, OP, O, OL, OAC, O2P, O2, O2L, O2AC]) P
 	return nil, ErrNotImplemented
 }
 
+// Apply supports the v1 and v1beta2 APIs. The v1beta2 types have the same
+// structure as the v1 types, so the apply configuration can be converted by
+// changing the API version. v1beta1 is different and not supported.
 func (t *convertingClient[NP, N, NL, NAC, OP, O, OL, OAC, O2P, O2, O2L, O2AC]) Apply(ctx context.Context, obj *NAC, opts metav1.ApplyOptions) (result *N, err error) {
-	return nil, ErrNotImplemented
+	apis := newCall(t.c, func(currentAPI int32) (*N, error) {
+		switch currentAPI {
+		case useV1beta1API:
+			return nil, fmt.Errorf("apply with the v1beta1 API: %w", ErrNotImplemented)
+		case useV1beta2API:
+			obj, err := convertApplyConfiguration[NAC, O2AC](obj, resourcev1beta2.SchemeGroupVersion.String())
+			if err != nil {
+				return nil, err
+			}
+			return getWithConversion[N](func() (*O2, error) {
+				return t.v1beta2.Apply(ctx, obj, opts)
+			})
+		default:
+			return t.native.Apply(ctx, obj, opts)
+		}
+	})
+	return apis.run()
 }
 
 func (t *convertingClient[NP, N, NL, NAC, OP, O, OL, OAC, O2P, O2, O2L, O2AC]) ApplyStatus(ctx context.Context, obj *NAC, opts metav1.ApplyOptions) (result *N, err error) {
@@ -295,6 +317,30 @@ func getWithConversion[N, O any](call func() (*O, error)) (*N, error) {
 	return value, nil
 }
 
+// convertApplyConfiguration converts between apply configurations for
+// types which have the same structure in different API versions.
+// Only the fields which are set in the input are set in the output.
+func convertApplyConfiguration[In, Out any](in *In, apiVersion string) (*Out, error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("encode apply configuration: %w", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("decode apply configuration: %w", err)
+	}
+	fields["apiVersion"] = apiVersion
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("encode apply configuration: %w", err)
+	}
+	out := new(Out)
+	if err := json.Unmarshal(data, out); err != nil {
+		return nil, fmt.Errorf("decode apply configuration for %s: %w", apiVersion, err)
+	}
+	return out, nil
+}
+
 func watchWithConversion[NP objectPtr[N], N any, OP runtime.Object](call func() (watch.Interface, error)) (watch.Interface, error) {
 	in, err := call()
 	if err != nil {
@@ -0,0 +1,172 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command dra-cel compiles a CEL device selector the same way as the
+// scheduler does and optionally evaluates it against the devices in
+// ResourceSlices read from a YAML or JSON file. No cluster is needed.
+//
+// Usage:
+//
+//	dra-cel [flags] <expression>
+//
+// Example:
+//
+//	dra-cel -slices slices.yaml 'device.attributes["dra.example.com"].model == "a100"'
+//
+// The file may contain several ResourceSlice objects of API version
+// resource.k8s.io/v1, separated by "---". "-" reads from stdin.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	resourceapi "k8s.io/api/resource/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apiserver/pkg/cel/environment"
+	"k8s.io/dynamic-resource-allocation/cel"
+	"k8s.io/utils/ptr"
+)
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run implements the command. It returns the exit code:
+// 0 for success, 1 for compile or runtime errors, 2 for invalid usage.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("dra-cel", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	slicesFile := flags.String("slices", "", "YAML or JSON file with ResourceSlices whose devices the expression gets evaluated against, - for stdin. If empty, the expression is only compiled.")
+	consumableCapacity := flags.Bool("consumable-capacity", false, "Enable the DRAConsumableCapacity feature (device.allowMultipleAllocations).")
+	listTypeAttributes := flags.Bool("list-type-attributes", false, "Enable the DRAListTypeAttributes feature (list attributes, includes function).")
+	schedulingFields := flags.Bool("device-scheduling-fields", false, "Enable device.taints, device.bindingConditions and device.consumesCounters (DRADeviceSchedulingFields).")
+	newExpression := flags.Bool("new-expression", false, "Compile like the apiserver does when validating a new object. By default, the expression is compiled like an already stored expression, which is how the scheduler handles it.")
+	costLimit := flags.Uint64("cost-limit", resourceapi.CELSelectorExpressionMaxCost, "Runtime cost limit.")
+	flags.Usage = func() {
+		_, _ = fmt.Fprintf(flags.Output(), "Usage: dra-cel [flags] <expression>\n\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	expression := flags.Arg(0)
+
+	features := cel.Features{
+		EnableConsumableCapacity:     *consumableCapacity,
+		EnableListTypeAttributes:     *listTypeAttributes,
+		EnableDeviceSchedulingFields: *schedulingFields,
+	}
+	options := cel.Options{
+		CostLimit: costLimit,
+	}
+	if *newExpression {
+		options.EnvType = ptr.To(environment.NewExpressions)
+	}
+	result := cel.GetCompiler(features).CompileCELExpression(expression, options)
+	if result.Error != nil {
+		_, _ = fmt.Fprintf(stderr, "Compile error: %v\n", result.Error)
+		return 1
+	}
+	_, _ = fmt.Fprintf(stdout, "Estimated cost: %d\n", result.MaxCost)
+	if result.MaxCost > *costLimit {
+		_, _ = fmt.Fprintf(stdout, "Warning: the estimated cost exceeds the limit of %d, the apiserver would reject this expression.\n", *costLimit)
+	}
+
+	if *slicesFile == "" {
+		return 0
+	}
+	slices, err := readSlices(*slicesFile, stdin)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	exitCode := 0
+	numMatches := 0
+	for _, slice := range slices {
+		for _, device := range slice.Spec.Devices {
+			id := slice.Spec.Driver + "/" + slice.Spec.Pool.Name + "/" + device.Name
+			matches, details, err := result.DeviceMatches(ctx, cel.Device{
+				Driver:                   slice.Spec.Driver,
+				AllowMultipleAllocations: device.AllowMultipleAllocations,
+				Attributes:               device.Attributes,
+				Capacity:                 device.Capacity,
+				Taints:                   device.Taints,
+				BindingConditions:        device.BindingConditions,
+				ConsumesCounters:         device.ConsumesCounters,
+			})
+			cost := ptr.Deref(details.ActualCost(), 0)
+			switch {
+			case err != nil:
+				_, _ = fmt.Fprintf(stdout, "%s: error (cost %d): %v\n", id, cost, cel.EnhanceRuntimeError(err))
+				exitCode = 1
+			case matches:
+				_, _ = fmt.Fprintf(stdout, "%s: match (cost %d)\n", id, cost)
+				numMatches++
+			default:
+				_, _ = fmt.Fprintf(stdout, "%s: no match (cost %d)\n", id, cost)
+			}
+		}
+	}
+	_, _ = fmt.Fprintf(stdout, "%d matching device(s)\n", numMatches)
+	return exitCode
+}
+
+// readSlices decodes all ResourceSlices in the file. Empty documents are skipped.
+func readSlices(fileName string, stdin io.Reader) ([]*resourceapi.ResourceSlice, error) {
+	in := stdin
+	if fileName != "-" {
+		file, err := os.Open(fileName)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = file.Close() }()
+		in = file
+	}
+
+	var slices []*resourceapi.ResourceSlice
+	decoder := utilyaml.NewYAMLOrJSONDecoder(in, 4096)
+	for i := 0; ; i++ {
+		var slice resourceapi.ResourceSlice
+		err := decoder.Decode(&slice)
+		if errors.Is(err, io.EOF) {
+			return slices, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: object #%d: %w", fileName, i, err)
+		}
+		if slice.APIVersion == "" && slice.Kind == "" && slice.Spec.Driver == "" {
+			// Empty document.
+			continue
+		}
+		if slice.APIVersion != resourceapi.SchemeGroupVersion.String() || slice.Kind != "ResourceSlice" {
+			return nil, fmt.Errorf("%s: object #%d: expected apiVersion %s and kind ResourceSlice, got apiVersion %q and kind %q", fileName, i, resourceapi.SchemeGroupVersion, slice.APIVersion, slice.Kind)
+		}
+		slices = append(slices, &slice)
+	}
+}
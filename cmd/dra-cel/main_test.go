@@ -0,0 +1,91 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	for name, tc := range map[string]struct {
+		args           []string
+		stdin          string
+		expectExitCode int
+		expectStdout   []string
+		expectStderr   string
+	}{
+		"no-args": {
+			expectExitCode: 2,
+			expectStderr:   "Usage: dra-cel",
+		},
+		"compile-only": {
+			args:         []string{`device.driver == "gpu.example.com"`},
+			expectStdout: []string{"Estimated cost: 4"},
+		},
+		"compile-error": {
+			args:           []string{`device.noSuchField`},
+			expectExitCode: 1,
+			expectStderr:   "Compile error:",
+		},
+		"matches": {
+			args: []string{"-slices", "testdata/slices.yaml", `device.attributes["gpu.example.com"].?model.orValue("") == "h100"`},
+			expectStdout: []string{
+				"gpu.example.com/worker-1/gpu-0: no match",
+				"gpu.example.com/worker-1/gpu-1: match",
+				"gpu.example.com/worker-1/gpu-2: no match",
+				"1 matching device(s)",
+			},
+		},
+		"runtime-error": {
+			args:           []string{"-slices", "testdata/slices.yaml", `device.attributes["gpu.example.com"].memoryGi >= 80`},
+			expectExitCode: 1,
+			expectStdout: []string{
+				"gpu.example.com/worker-1/gpu-1: match",
+				"gpu.example.com/worker-1/gpu-2: error",
+				"consider using CEL optional chaining",
+			},
+		},
+		"stdin": {
+			args:  []string{"-slices", "-", `device.driver == "other.example.com"`},
+			stdin: "apiVersion: resource.k8s.io/v1\nkind: ResourceSlice\nspec:\n  driver: other.example.com\n  pool:\n    name: pool\n  devices:\n  - name: dev\n",
+			expectStdout: []string{
+				"other.example.com/pool/dev: match",
+			},
+		},
+		"wrong-version": {
+			args:           []string{"-slices", "-", `true`},
+			stdin:          "apiVersion: resource.k8s.io/v1beta1\nkind: ResourceSlice\nspec:\n  driver: other.example.com\n",
+			expectExitCode: 1,
+			expectStderr:   `expected apiVersion resource.k8s.io/v1 and kind ResourceSlice, got apiVersion "resource.k8s.io/v1beta1"`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			exitCode := run(context.Background(), tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+			assert.Equal(t, tc.expectExitCode, exitCode, "exit code")
+			for _, expect := range tc.expectStdout {
+				assert.Contains(t, stdout.String(), expect, "stdout")
+			}
+			assert.Contains(t, stderr.String(), tc.expectStderr, "stderr")
+		})
+	}
+}
@@ -0,0 +1,233 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package selectorregistry compiles the CEL selectors of DeviceClasses
+// as soon as the classes get created or updated. Compile errors get
+// reported as Events for the DeviceClass, so broken classes are noticed
+// before scheduling tries to use them.
+package selectorregistry
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/dynamic-resource-allocation/cel"
+	klog "k8s.io/klog/v2"
+)
+
+// ReasonInvalidSelector is the reason of the Warning Event which gets
+// emitted for a DeviceClass with a selector that cannot be compiled.
+const ReasonInvalidSelector = "InvalidSelector"
+
+// Registry maintains the compiled selectors of all DeviceClasses,
+// based on informer events. For that it implements the cache.ResourceEventHandler interface.
+//
+// Compilation goes through the [cel.Cache] passed to [NewRegistry].
+// Allocators which implement structured.AllocatorWithSelectorRegistry
+// use the compiled selectors directly, so they remain available even
+// when the cache evicts them.
+type Registry struct {
+	logger   klog.Logger
+	celCache *cel.Cache
+	recorder record.EventRecorder
+	handlers []cache.ResourceEventHandler
+
+	mutex sync.RWMutex
+	// classes maps the device class name to its compiled selectors.
+	classes map[string]*Selectors
+}
+
+// Selectors contains the result of compiling the selectors of one DeviceClass.
+type Selectors struct {
+	// Class is the DeviceClass which was compiled.
+	Class *resourceapi.DeviceClass
+	// Results has one entry per selector in the class, in the same order.
+	// Entries of selectors without a CEL expression are empty.
+	Results []cel.CompilationResult
+	// Err is non-nil if at least one selector failed to compile.
+	// It combines all compile errors.
+	Err error
+}
+
+var _ cache.ResourceEventHandler = &Registry{}
+
+// NewRegistry creates a new Registry instance. The caller
+// is responsible for registering the instance as a handler of DeviceClass events.
+//
+// The recorder is optional. If nil, compile errors only get logged.
+//
+// Additional event handlers may be registered here or via AddEventHandler.
+func NewRegistry(logger klog.Logger, celCache *cel.Cache, recorder record.EventRecorder, handlers ...cache.ResourceEventHandler) *Registry {
+	return &Registry{
+		logger:   logger,
+		celCache: celCache,
+		recorder: recorder,
+		handlers: handlers,
+		classes:  make(map[string]*Selectors),
+	}
+}
+
+// AddEventHandler adds an event handler which gets called after the registry
+// has processed some incoming event. More than one additional event handler
+// may be added. They will be called in the order in which they were registered.
+// GetSelectors may be called from those event handlers.
+//
+// Not thread-safe, must be called *before* adding the registry itself to an
+// informer.
+func (r *Registry) AddEventHandler(handler cache.ResourceEventHandler) {
+	r.handlers = append(r.handlers, handler)
+}
+
+// GetSelectors returns the compiled selectors for the device class.
+// Returns nil if the class is unknown.
+//
+// The result is shared and must not be modified.
+//
+// This (and only this) method may be called on a nil Registry. The nil
+// instance always returns nil.
+func (r *Registry) GetSelectors(className string) *Selectors {
+	if r == nil {
+		return nil
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.classes[className]
+}
+
+// Features returns the CEL features used for compiling selectors.
+// Users of the compiled selectors must check that those match the
+// features they use themselves.
+func (r *Registry) Features() cel.Features {
+	return r.celCache.Features()
+}
+
+// InvalidClasses returns the names of all device classes with selectors
+// that failed to compile, sorted by name.
+func (r *Registry) InvalidClasses() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var names []string
+	for name, selectors := range r.classes {
+		if selectors.Err != nil {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// OnAdd handles the addition of a new device class.
+func (r *Registry) OnAdd(obj interface{}, isInInitialList bool) {
+	deviceClass, ok := obj.(*resourceapi.DeviceClass)
+	if !ok {
+		utilruntime.HandleErrorWithLogger(r.logger, nil, "Expected DeviceClass", "actual", fmt.Sprintf("%T", obj))
+		return
+	}
+	r.logger.V(5).Info("DeviceClass added", "deviceClass", klog.KObj(deviceClass))
+	r.compile(deviceClass)
+
+	for _, handler := range r.handlers {
+		handler.OnAdd(obj, isInInitialList)
+	}
+}
+
+// OnUpdate handles updates to an existing device class.
+func (r *Registry) OnUpdate(oldObj, newObj interface{}) {
+	deviceClass, ok := newObj.(*resourceapi.DeviceClass)
+	if !ok {
+		utilruntime.HandleErrorWithLogger(r.logger, nil, "Expected DeviceClass", "actual", fmt.Sprintf("%T", newObj))
+		return
+	}
+	r.logger.V(5).Info("DeviceClass updated", "deviceClass", klog.KObj(deviceClass))
+	r.compile(deviceClass)
+
+	for _, handler := range r.handlers {
+		handler.OnUpdate(oldObj, newObj)
+	}
+}
+
+// OnDelete handles deletion of a device class.
+func (r *Registry) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	deviceClass, ok := obj.(*resourceapi.DeviceClass)
+	if !ok {
+		utilruntime.HandleErrorWithLogger(r.logger, nil, "Expected DeviceClass", "actual", fmt.Sprintf("%T", obj))
+		return
+	}
+	r.logger.V(5).Info("DeviceClass deleted", "deviceClass", klog.KObj(deviceClass))
+	r.mutex.Lock()
+	delete(r.classes, deviceClass.Name)
+	r.mutex.Unlock()
+
+	for _, handler := range r.handlers {
+		handler.OnDelete(obj)
+	}
+}
+
+// compile stores the compiled selectors of the class. Compilation
+// and error reporting get skipped if the selectors are unchanged.
+func (r *Registry) compile(newDeviceClass *resourceapi.DeviceClass) {
+	r.mutex.RLock()
+	existing := r.classes[newDeviceClass.Name]
+	r.mutex.RUnlock()
+	if existing != nil && apiequality.Semantic.DeepEqual(existing.Class.Spec.Selectors, newDeviceClass.Spec.Selectors) {
+		r.mutex.Lock()
+		r.classes[newDeviceClass.Name] = &Selectors{Class: newDeviceClass, Results: existing.Results, Err: existing.Err}
+		r.mutex.Unlock()
+		return
+	}
+
+	selectors := &Selectors{
+		Class:   newDeviceClass,
+		Results: make([]cel.CompilationResult, len(newDeviceClass.Spec.Selectors)),
+	}
+	var errs []error
+	for i, selector := range newDeviceClass.Spec.Selectors {
+		if selector.CEL == nil {
+			continue
+		}
+		result := r.celCache.GetOrCompile(selector.CEL.Expression)
+		selectors.Results[i] = result
+		if result.Error != nil {
+			errs = append(errs, fmt.Errorf("selector #%d: %w", i, result.Error))
+		}
+	}
+	selectors.Err = errors.Join(errs...)
+
+	r.mutex.Lock()
+	r.classes[newDeviceClass.Name] = selectors
+	r.mutex.Unlock()
+
+	if selectors.Err == nil {
+		r.logger.V(5).Info("Compiled DeviceClass selectors", "deviceClass", klog.KObj(newDeviceClass), "numSelectors", len(selectors.Results))
+		return
+	}
+	r.logger.Error(selectors.Err, "DeviceClass has invalid CEL selectors", "deviceClass", klog.KObj(newDeviceClass))
+	if r.recorder != nil {
+		r.recorder.Eventf(newDeviceClass, v1.EventTypeWarning, ReasonInvalidSelector, "CEL selectors cannot be compiled, devices from this class cannot be allocated: %v", selectors.Err)
+	}
+}
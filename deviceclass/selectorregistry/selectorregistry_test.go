@@ -0,0 +1,138 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selectorregistry
+
+import (
+	"strings"
+	"testing"
+
+	resourceapi "k8s.io/api/resource/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/dynamic-resource-allocation/cel"
+	"k8s.io/klog/v2/ktesting"
+	_ "k8s.io/klog/v2/ktesting/init" // Add command line flags.
+)
+
+func classWithSelectors(name string, expressions ...string) *resourceapi.DeviceClass {
+	class := &resourceapi.DeviceClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+	for _, expression := range expressions {
+		class.Spec.Selectors = append(class.Spec.Selectors, resourceapi.DeviceSelector{
+			CEL: &resourceapi.CELDeviceSelector{Expression: expression},
+		})
+	}
+	return class
+}
+
+func TestNil(t *testing.T) {
+	var registry *Registry
+	if selectors := registry.GetSelectors("gpu-class"); selectors != nil {
+		t.Errorf("Expected nil selectors from a nil instance, got instead: %v", selectors)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	logger, _ := ktesting.NewTestContext(t)
+	recorder := record.NewFakeRecorder(10)
+	celCache := cel.NewCache(10, cel.Features{})
+	var numHandlerCalls int
+	registry := NewRegistry(logger, celCache, recorder, &cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { numHandlerCalls++ },
+		UpdateFunc: func(oldObj, newObj interface{}) { numHandlerCalls++ },
+		DeleteFunc: func(obj interface{}) { numHandlerCalls++ },
+	})
+	expectEvents := func(t *testing.T, expected int) {
+		t.Helper()
+		for i := 0; i < expected; i++ {
+			select {
+			case event := <-recorder.Events:
+				if !strings.Contains(event, "Warning "+ReasonInvalidSelector) {
+					t.Errorf("unexpected event: %s", event)
+				}
+			default:
+				t.Fatalf("expected %d event(s), got %d", expected, i)
+			}
+		}
+		select {
+		case event := <-recorder.Events:
+			t.Fatalf("unexpected event: %s", event)
+		default:
+		}
+	}
+
+	validClass := classWithSelectors("valid", `device.driver == "dra.example.com"`)
+	registry.OnAdd(validClass, true)
+	selectors := registry.GetSelectors("valid")
+	if selectors == nil {
+		t.Fatal("expected selectors for valid class")
+	}
+	if selectors.Err != nil {
+		t.Errorf("unexpected error: %v", selectors.Err)
+	}
+	if len(selectors.Results) != 1 || selectors.Results[0].Program == nil {
+		t.Errorf("expected one compiled program, got %+v", selectors.Results)
+	}
+	expectEvents(t, 0)
+
+	invalidClass := classWithSelectors("invalid", `device.driver == "dra.example.com"`, `device.no_such_field`)
+	registry.OnAdd(invalidClass, false)
+	selectors = registry.GetSelectors("invalid")
+	if selectors == nil || selectors.Err == nil {
+		t.Fatalf("expected compile error, got %+v", selectors)
+	}
+	if !strings.Contains(selectors.Err.Error(), "selector #1:") {
+		t.Errorf("expected error for selector #1, got: %v", selectors.Err)
+	}
+	expectEvents(t, 1)
+	if actual := registry.InvalidClasses(); len(actual) != 1 || actual[0] != "invalid" {
+		t.Errorf("expected invalid class, got %v", actual)
+	}
+
+	// An update which doesn't touch the selectors doesn't trigger another Event.
+	updatedInvalidClass := invalidClass.DeepCopy()
+	updatedInvalidClass.Labels = map[string]string{"a": "b"}
+	registry.OnUpdate(invalidClass, updatedInvalidClass)
+	expectEvents(t, 0)
+	if class := registry.GetSelectors("invalid").Class; class != updatedInvalidClass {
+		t.Errorf("expected updated class, got %v", class)
+	}
+
+	// Fixing the selector gets reflected.
+	fixedClass := classWithSelectors("invalid", `device.driver == "dra.example.com"`)
+	registry.OnUpdate(updatedInvalidClass, fixedClass)
+	expectEvents(t, 0)
+	if selectors := registry.GetSelectors("invalid"); selectors.Err != nil {
+		t.Errorf("unexpected error after update: %v", selectors.Err)
+	}
+	if actual := registry.InvalidClasses(); len(actual) != 0 {
+		t.Errorf("expected no invalid classes, got %v", actual)
+	}
+
+	registry.OnDelete(cache.DeletedFinalStateUnknown{Obj: fixedClass})
+	if selectors := registry.GetSelectors("invalid"); selectors != nil {
+		t.Errorf("expected no selectors after delete, got %+v", selectors)
+	}
+
+	if numHandlerCalls != 5 {
+		t.Errorf("expected 5 calls of the additional event handler, got %d", numHandlerCalls)
+	}
+}
@@ -0,0 +1,315 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package poolvalidation contains checks of a resource pool which span
+// several ResourceSlices or several devices and therefore cannot be done
+// by the apiserver when validating a single ResourceSlice. It is shared
+// between the ResourceSlice controller, which validates before publishing,
+// and the allocator, which validates before allocating.
+package poolvalidation
+
+import (
+	"fmt"
+	"strings"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+)
+
+// Slice is one ResourceSlice of a pool.
+type Slice struct {
+	// Path is the root for all errors found in this slice.
+	Path *field.Path
+	// Spec is the content of the slice. Driver is used
+	// to qualify attribute names which have no domain.
+	Spec *resourceapi.ResourceSliceSpec
+}
+
+// Options control which checks get performed.
+// The zero value enables all checks.
+type Options struct {
+	// IgnoreCounters disables all checks related to shared counters
+	// and counter consumption. The allocator uses this when
+	// partitionable devices are disabled.
+	IgnoreCounters bool
+
+	// SkipAPIServerChecks disables those checks of individual devices
+	// which the apiserver also performs when validating a single
+	// ResourceSlice, i.e. capacity request policies, node selection
+	// and binding conditions. The allocator uses this because it
+	// only gets ResourceSlices which passed validation.
+	SkipAPIServerChecks bool
+
+	// SkipPublishingChecks disables the checks for devices which
+	// consume more of a counter than is available and for attributes
+	// with different types. Such mistakes of a driver get caught
+	// before publishing, but they do not prevent allocating other
+	// devices from the pool, so the allocator uses this to avoid
+	// treating the entire pool as invalid.
+	SkipPublishingChecks bool
+}
+
+// ValidatePool checks all slices of one pool with the same generation.
+// It detects:
+//   - duplicate device and counter set names
+//   - counter sets and counters which are referenced by a device
+//     but do not exist
+//   - devices which consume more of a counter than is available
+//     in the counter set, because those can never be allocated,
+//     unless disabled
+//   - attributes with the same name but different types, unless
+//     disabled
+//
+// Unless disabled, it also checks some fields of individual devices
+// before the apiserver gets to see them:
+//   - inconsistent capacity request policies
+//   - per-device node selection fields which are not set exactly
+//     once when the slice uses per-device node selection, or which
+//     are set when it doesn't
+//   - too many binding conditions
+//
+// Errors are reported per device with the path of the problematic field.
+func ValidatePool(slices []Slice, options Options) field.ErrorList {
+	var allErrs field.ErrorList
+
+	counterSets := make(map[string]*resourceapi.CounterSet)
+	if !options.IgnoreCounters {
+		for _, slice := range slices {
+			for i := range slice.Spec.SharedCounters {
+				counterSet := &slice.Spec.SharedCounters[i]
+				if _, found := counterSets[counterSet.Name]; found {
+					allErrs = append(allErrs, field.Duplicate(slice.Path.Child("sharedCounters").Index(i).Child("name"), counterSet.Name))
+					continue
+				}
+				counterSets[counterSet.Name] = counterSet
+			}
+		}
+	}
+
+	deviceNames := sets.New[string]()
+	attributeTypes := make(map[resourceapi.QualifiedName]attributeType)
+	for _, slice := range slices {
+		perDeviceNodeSelection := ptr.Deref(slice.Spec.PerDeviceNodeSelection, false)
+		for i := range slice.Spec.Devices {
+			device := &slice.Spec.Devices[i]
+			fldPath := slice.Path.Child("devices").Index(i)
+			if deviceNames.Has(device.Name) {
+				allErrs = append(allErrs, field.Duplicate(fldPath.Child("name"), device.Name))
+			}
+			deviceNames.Insert(device.Name)
+
+			if !options.IgnoreCounters {
+				allErrs = append(allErrs, validateCounterConsumption(device, counterSets, !options.SkipPublishingChecks, fldPath.Child("consumesCounters"))...)
+			}
+			if !options.SkipPublishingChecks {
+				allErrs = append(allErrs, validateAttributeTypes(slice.Spec.Driver, device, attributeTypes, fldPath.Child("attributes"))...)
+			}
+			if options.SkipAPIServerChecks {
+				continue
+			}
+			allErrs = append(allErrs, validateCapacity(device, fldPath.Child("capacity"))...)
+			allErrs = append(allErrs, validateNodeSelection(device, perDeviceNodeSelection, fldPath)...)
+			if len(device.BindingConditions) > resourceapi.BindingConditionsMaxSize {
+				allErrs = append(allErrs, field.TooMany(fldPath.Child("bindingConditions"), len(device.BindingConditions), resourceapi.BindingConditionsMaxSize))
+			}
+			if len(device.BindingFailureConditions) > resourceapi.BindingFailureConditionsMaxSize {
+				allErrs = append(allErrs, field.TooMany(fldPath.Child("bindingFailureConditions"), len(device.BindingFailureConditions), resourceapi.BindingFailureConditionsMaxSize))
+			}
+		}
+	}
+	return allErrs
+}
+
+func validateCounterConsumption(device *resourceapi.Device, counterSets map[string]*resourceapi.CounterSet, checkAvailable bool, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, consumption := range device.ConsumesCounters {
+		idxPath := fldPath.Index(i)
+		counterSet, found := counterSets[consumption.CounterSet]
+		if !found {
+			allErrs = append(allErrs, field.NotFound(idxPath.Child("counterSet"), consumption.CounterSet))
+			continue
+		}
+		for _, counterName := range sets.List(sets.KeySet(consumption.Counters)) {
+			counterPath := idxPath.Child("counters").Key(counterName)
+			available, found := counterSet.Counters[counterName]
+			if !found {
+				allErrs = append(allErrs, field.Invalid(counterPath, counterName, fmt.Sprintf("counter not found in counter set %q", counterSet.Name)))
+				continue
+			}
+			if !checkAvailable {
+				continue
+			}
+			consumed := consumption.Counters[counterName].Value
+			if consumed.Cmp(available.Value) > 0 {
+				allErrs = append(allErrs, field.Invalid(counterPath.Child("value"), consumed.String(), fmt.Sprintf("exceeds the %s available in counter set %q", available.Value.String(), counterSet.Name)))
+			}
+		}
+	}
+	return allErrs
+}
+
+type attributeType struct {
+	name string
+	path *field.Path
+}
+
+func validateAttributeTypes(driver string, device *resourceapi.Device, attributeTypes map[resourceapi.QualifiedName]attributeType, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, name := range sets.List(sets.KeySet(device.Attributes)) {
+		typeName := attributeTypeName(device.Attributes[name])
+		if typeName == "" {
+			// Empty or unknown, validated by the apiserver.
+			continue
+		}
+		attrPath := fldPath.Key(string(name))
+		qualifiedName := name
+		if !strings.Contains(string(qualifiedName), "/") {
+			qualifiedName = resourceapi.QualifiedName(driver + "/" + string(name))
+		}
+		previous, found := attributeTypes[qualifiedName]
+		if !found {
+			attributeTypes[qualifiedName] = attributeType{name: typeName, path: attrPath}
+			continue
+		}
+		if previous.name != typeName {
+			allErrs = append(allErrs, field.Invalid(attrPath, typeName, fmt.Sprintf("must have the same type as %s (%s)", previous.path, previous.name)))
+		}
+	}
+	return allErrs
+}
+
+// attributeTypeName returns the type of the attribute values. A list
+// has the same type as a single value because constraints
+// treat them the same way.
+func attributeTypeName(attribute resourceapi.DeviceAttribute) string {
+	switch {
+	case attribute.IntValue != nil, attribute.IntValues != nil:
+		return "int"
+	case attribute.BoolValue != nil, attribute.BoolValues != nil:
+		return "bool"
+	case attribute.StringValue != nil, attribute.StringValues != nil:
+		return "string"
+	case attribute.VersionValue != nil, attribute.VersionValues != nil:
+		return "version"
+	default:
+		return ""
+	}
+}
+
+func validateCapacity(device *resourceapi.Device, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, name := range sets.List(sets.KeySet(device.Capacity)) {
+		capacity := device.Capacity[name]
+		policy := capacity.RequestPolicy
+		if policy == nil {
+			continue
+		}
+		policyPath := fldPath.Key(string(name)).Child("requestPolicy")
+		value := capacity.Value
+		if policy.Default != nil && policy.Default.Cmp(value) > 0 {
+			allErrs = append(allErrs, field.Invalid(policyPath.Child("default"), policy.Default.String(), fmt.Sprintf("must not exceed the capacity value %s", value.String())))
+		}
+		if len(policy.ValidValues) > 0 {
+			validValuesPath := policyPath.Child("validValues")
+			foundDefault := false
+			for i, validValue := range policy.ValidValues {
+				if validValue.Cmp(value) > 0 {
+					allErrs = append(allErrs, field.Invalid(validValuesPath.Index(i), validValue.String(), fmt.Sprintf("must not exceed the capacity value %s", value.String())))
+				}
+				if i > 0 && validValue.Cmp(policy.ValidValues[i-1]) <= 0 {
+					allErrs = append(allErrs, field.Invalid(validValuesPath.Index(i), validValue.String(), "must be sorted in ascending order without duplicates"))
+				}
+				if policy.Default != nil && policy.Default.Cmp(validValue) == 0 {
+					foundDefault = true
+				}
+			}
+			if policy.Default == nil {
+				allErrs = append(allErrs, field.Required(policyPath.Child("default"), "required when validValues are set"))
+			} else if !foundDefault {
+				allErrs = append(allErrs, field.Invalid(policyPath.Child("default"), policy.Default.String(), "must be one of the valid values"))
+			}
+		}
+		if validRange := policy.ValidRange; validRange != nil {
+			rangePath := policyPath.Child("validRange")
+			if validRange.Min == nil {
+				allErrs = append(allErrs, field.Required(rangePath.Child("min"), ""))
+				continue
+			}
+			if validRange.Min.Cmp(value) > 0 {
+				allErrs = append(allErrs, field.Invalid(rangePath.Child("min"), validRange.Min.String(), fmt.Sprintf("must not exceed the capacity value %s", value.String())))
+			}
+			if validRange.Max != nil {
+				if validRange.Max.Cmp(*validRange.Min) < 0 {
+					allErrs = append(allErrs, field.Invalid(rangePath.Child("max"), validRange.Max.String(), fmt.Sprintf("must not be smaller than min %s", validRange.Min.String())))
+				}
+				if validRange.Max.Cmp(value) > 0 {
+					allErrs = append(allErrs, field.Invalid(rangePath.Child("max"), validRange.Max.String(), fmt.Sprintf("must not exceed the capacity value %s", value.String())))
+				}
+			}
+			if validRange.Step != nil {
+				// Same integer arithmetic as in the allocator.
+				step := validRange.Step.Value()
+				stepPath := rangePath.Child("step")
+				switch {
+				case step <= 0:
+					allErrs = append(allErrs, field.Invalid(stepPath, validRange.Step.String(), "must be positive"))
+				case validRange.Min.Value()+step > value.Value():
+					allErrs = append(allErrs, field.Invalid(stepPath, validRange.Step.String(), fmt.Sprintf("min plus step must not exceed the capacity value %s", value.String())))
+				default:
+					if validRange.Max != nil && validRange.Max.Value()%step != 0 {
+						allErrs = append(allErrs, field.Invalid(rangePath.Child("max"), validRange.Max.String(), "must be a multiple of step"))
+					}
+					if policy.Default != nil && policy.Default.Value()%step != 0 {
+						allErrs = append(allErrs, field.Invalid(policyPath.Child("default"), policy.Default.String(), "must be a multiple of step"))
+					}
+				}
+			}
+			if policy.Default == nil {
+				allErrs = append(allErrs, field.Required(policyPath.Child("default"), "required when validRange is set"))
+			} else if policy.Default.Cmp(*validRange.Min) < 0 || (validRange.Max != nil && policy.Default.Cmp(*validRange.Max) > 0) {
+				allErrs = append(allErrs, field.Invalid(policyPath.Child("default"), policy.Default.String(), "must be within the valid range"))
+			}
+		}
+	}
+	return allErrs
+}
+
+func validateNodeSelection(device *resourceapi.Device, perDeviceNodeSelection bool, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	var fields []string
+	if device.NodeName != nil {
+		fields = append(fields, "nodeName")
+	}
+	if device.NodeSelector != nil {
+		fields = append(fields, "nodeSelector")
+	}
+	if device.AllNodes != nil {
+		fields = append(fields, "allNodes")
+	}
+	switch {
+	case !perDeviceNodeSelection:
+		for _, name := range fields {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child(name), "only allowed when perDeviceNodeSelection is true"))
+		}
+	case len(fields) == 0:
+		allErrs = append(allErrs, field.Required(fldPath, "exactly one of nodeName, nodeSelector or allNodes is required when perDeviceNodeSelection is true"))
+	case len(fields) > 1:
+		allErrs = append(allErrs, field.Invalid(fldPath, strings.Join(fields, ", "), "only one of nodeName, nodeSelector or allNodes may be set"))
+	}
+	return allErrs
+}
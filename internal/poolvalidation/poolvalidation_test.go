@@ -0,0 +1,261 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package poolvalidation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/ptr"
+)
+
+const driverName = "driver.example.com"
+
+func TestValidatePool(t *testing.T) {
+	slice0 := field.NewPath("slices").Index(0)
+	slice1 := field.NewPath("slices").Index(1)
+	device0 := slice0.Child("devices").Index(0)
+	device1 := slice0.Child("devices").Index(1)
+	counterSet := resourceapi.CounterSet{
+		Name: "gpu-0",
+		Counters: map[string]resourceapi.Counter{
+			"memory": {Value: resource.MustParse("8Gi")},
+		},
+	}
+	consumes := func(counterSet, counter, value string) []resourceapi.DeviceCounterConsumption {
+		return []resourceapi.DeviceCounterConsumption{{
+			CounterSet: counterSet,
+			Counters:   map[string]resourceapi.Counter{counter: {Value: resource.MustParse(value)}},
+		}}
+	}
+	capacity := func(value string, policy resourceapi.CapacityRequestPolicy) map[resourceapi.QualifiedName]resourceapi.DeviceCapacity {
+		return map[resourceapi.QualifiedName]resourceapi.DeviceCapacity{
+			"bandwidth": {Value: resource.MustParse(value), RequestPolicy: &policy},
+		}
+	}
+	quantity := func(value string) *resource.Quantity {
+		q := resource.MustParse(value)
+		return &q
+	}
+	policyPath := device0.Child("capacity").Key("bandwidth").Child("requestPolicy")
+
+	testcases := map[string]struct {
+		slices       []resourceapi.ResourceSliceSpec
+		options      Options
+		expectErrors field.ErrorList
+	}{
+		"valid": {
+			slices: []resourceapi.ResourceSliceSpec{
+				{SharedCounters: []resourceapi.CounterSet{counterSet}},
+				{Devices: []resourceapi.Device{
+					{Name: "a", ConsumesCounters: consumes("gpu-0", "memory", "4Gi")},
+					{Name: "b", ConsumesCounters: consumes("gpu-0", "memory", "8Gi")},
+				}},
+			},
+		},
+		"duplicate-counter-set": {
+			slices: []resourceapi.ResourceSliceSpec{
+				{SharedCounters: []resourceapi.CounterSet{counterSet}},
+				{SharedCounters: []resourceapi.CounterSet{counterSet}},
+			},
+			expectErrors: field.ErrorList{
+				field.Duplicate(slice1.Child("sharedCounters").Index(0).Child("name"), "gpu-0"),
+			},
+		},
+		"duplicate-device": {
+			slices: []resourceapi.ResourceSliceSpec{
+				{Devices: []resourceapi.Device{{Name: "a"}}},
+				{Devices: []resourceapi.Device{{Name: "a"}}},
+			},
+			expectErrors: field.ErrorList{
+				field.Duplicate(slice1.Child("devices").Index(0).Child("name"), "a"),
+			},
+		},
+		"unknown-counter-set": {
+			slices: []resourceapi.ResourceSliceSpec{
+				{Devices: []resourceapi.Device{{Name: "a", ConsumesCounters: consumes("gpu-1", "memory", "4Gi")}}},
+			},
+			expectErrors: field.ErrorList{
+				field.NotFound(device0.Child("consumesCounters").Index(0).Child("counterSet"), "gpu-1"),
+			},
+		},
+		"ignore-counters": {
+			slices: []resourceapi.ResourceSliceSpec{
+				{Devices: []resourceapi.Device{{Name: "a", ConsumesCounters: consumes("gpu-1", "memory", "4Gi")}}},
+			},
+			options: Options{IgnoreCounters: true},
+		},
+		"unknown-counter": {
+			slices: []resourceapi.ResourceSliceSpec{
+				{Devices: []resourceapi.Device{{Name: "a", ConsumesCounters: consumes("gpu-0", "cores", "1")}}},
+				{SharedCounters: []resourceapi.CounterSet{counterSet}},
+			},
+			expectErrors: field.ErrorList{
+				field.Invalid(device0.Child("consumesCounters").Index(0).Child("counters").Key("cores"), "cores", `counter not found in counter set "gpu-0"`),
+			},
+		},
+		"overconsumption": {
+			slices: []resourceapi.ResourceSliceSpec{
+				{Devices: []resourceapi.Device{{Name: "a", ConsumesCounters: consumes("gpu-0", "memory", "16Gi")}}},
+				{SharedCounters: []resourceapi.CounterSet{counterSet}},
+			},
+			expectErrors: field.ErrorList{
+				field.Invalid(device0.Child("consumesCounters").Index(0).Child("counters").Key("memory").Child("value"), "16Gi", `exceeds the 8Gi available in counter set "gpu-0"`),
+			},
+		},
+		"attribute-types": {
+			slices: []resourceapi.ResourceSliceSpec{
+				{
+					Driver: driverName,
+					Devices: []resourceapi.Device{
+						{Name: "a", Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							"model": {StringValue: ptr.To("a100")},
+							"numa":  {IntValue: ptr.To(int64(0))},
+						}},
+						{Name: "b", Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							driverName + "/model": {IntValue: ptr.To(int64(100))},
+							"numa":                {IntValues: []int64{0, 1}},
+						}},
+					},
+				},
+			},
+			expectErrors: field.ErrorList{
+				field.Invalid(device1.Child("attributes").Key(driverName+"/model"), "int", `must have the same type as slices[0].devices[0].attributes[model] (string)`),
+			},
+		},
+		"valid-values": {
+			slices: []resourceapi.ResourceSliceSpec{
+				{Devices: []resourceapi.Device{{Name: "a", Capacity: capacity("10", resourceapi.CapacityRequestPolicy{
+					Default:     quantity("3"),
+					ValidValues: []resource.Quantity{resource.MustParse("2"), resource.MustParse("1"), resource.MustParse("20")},
+				})}}},
+			},
+			expectErrors: field.ErrorList{
+				field.Invalid(policyPath.Child("validValues").Index(1), "1", "must be sorted in ascending order without duplicates"),
+				field.Invalid(policyPath.Child("validValues").Index(2), "20", "must not exceed the capacity value 10"),
+				field.Invalid(policyPath.Child("default"), "3", "must be one of the valid values"),
+			},
+		},
+		"valid-range": {
+			slices: []resourceapi.ResourceSliceSpec{
+				{Devices: []resourceapi.Device{{Name: "a", Capacity: capacity("10", resourceapi.CapacityRequestPolicy{
+					Default: quantity("12"),
+					ValidRange: &resourceapi.CapacityRequestPolicyRange{
+						Min:  quantity("2"),
+						Max:  quantity("9"),
+						Step: quantity("2"),
+					},
+				})}}},
+			},
+			expectErrors: field.ErrorList{
+				field.Invalid(policyPath.Child("default"), "12", "must not exceed the capacity value 10"),
+				field.Invalid(policyPath.Child("validRange", "max"), "9", "must be a multiple of step"),
+				field.Invalid(policyPath.Child("default"), "12", "must be within the valid range"),
+			},
+		},
+		"valid-range-step-too-large": {
+			slices: []resourceapi.ResourceSliceSpec{
+				{Devices: []resourceapi.Device{{Name: "a", Capacity: capacity("10", resourceapi.CapacityRequestPolicy{
+					Default: quantity("2"),
+					ValidRange: &resourceapi.CapacityRequestPolicyRange{
+						Min:  quantity("2"),
+						Step: quantity("10"),
+					},
+				})}}},
+			},
+			expectErrors: field.ErrorList{
+				field.Invalid(policyPath.Child("validRange", "step"), "10", "min plus step must not exceed the capacity value 10"),
+			},
+		},
+		"node-selection": {
+			slices: []resourceapi.ResourceSliceSpec{
+				{
+					PerDeviceNodeSelection: ptr.To(true),
+					Devices: []resourceapi.Device{
+						{Name: "a"},
+						{Name: "b", NodeName: ptr.To("node-a"), AllNodes: ptr.To(true)},
+						{Name: "c", NodeSelector: &v1.NodeSelector{}},
+					},
+				},
+				{
+					Devices: []resourceapi.Device{
+						{Name: "d", NodeName: ptr.To("node-a")},
+					},
+				},
+			},
+			expectErrors: field.ErrorList{
+				field.Required(device0, "exactly one of nodeName, nodeSelector or allNodes is required when perDeviceNodeSelection is true"),
+				field.Invalid(device1, "nodeName, allNodes", "only one of nodeName, nodeSelector or allNodes may be set"),
+				field.Forbidden(slice1.Child("devices").Index(0).Child("nodeName"), "only allowed when perDeviceNodeSelection is true"),
+			},
+		},
+		"binding-conditions": {
+			slices: []resourceapi.ResourceSliceSpec{
+				{Devices: []resourceapi.Device{{
+					Name:                     "a",
+					BindingConditions:        []string{"a", "b", "c", "d", "e"},
+					BindingFailureConditions: []string{"f"},
+				}}},
+			},
+			expectErrors: field.ErrorList{
+				field.TooMany(device0.Child("bindingConditions"), 5, resourceapi.BindingConditionsMaxSize),
+			},
+		},
+		"skip-apiserver-checks": {
+			slices: []resourceapi.ResourceSliceSpec{
+				{Devices: []resourceapi.Device{{
+					Name:              "a",
+					NodeName:          ptr.To("node-a"),
+					BindingConditions: []string{"a", "b", "c", "d", "e"},
+				}}},
+			},
+			options: Options{SkipAPIServerChecks: true},
+		},
+		"skip-publishing-checks": {
+			slices: []resourceapi.ResourceSliceSpec{
+				{SharedCounters: []resourceapi.CounterSet{counterSet}},
+				{
+					Driver: driverName,
+					Devices: []resourceapi.Device{
+						{Name: "a", ConsumesCounters: consumes("gpu-0", "memory", "16Gi"), Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							"model": {StringValue: ptr.To("a100")},
+						}},
+						{Name: "b", Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+							"model": {IntValue: ptr.To(int64(100))},
+						}},
+					},
+				},
+			},
+			options: Options{SkipPublishingChecks: true},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			slices := make([]Slice, len(tc.slices))
+			for i := range tc.slices {
+				slices[i] = Slice{Path: field.NewPath("slices").Index(i), Spec: &tc.slices[i]}
+			}
+			assert.Equal(t, tc.expectErrors, ValidatePool(slices, tc.options))
+		})
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"os"
 	"path"
 	"sync"
+	"time"
 
 	"google.golang.org/grpc"
 	"k8s.io/klog/v2"
@@ -583,6 +584,24 @@ func ReconcilePoolWithName(name string) Option {
 	}
 }
 
+// ShutdownTaint enables tainting all published devices when [Helper.Stop]
+// gets called, for example with a NoSchedule taint so that the scheduler
+// does not allocate devices while the driver is not running. Stop
+// waits for at most the given timeout for the ResourceSlices to be
+// updated. Stopping through context cancellation does not set the taint.
+//
+// The taint gets removed when the driver starts again and republishes
+// its devices. This should not be used together with [RollingUpdate]
+// because then the new instance is already running while the old one
+// shuts down.
+func ShutdownTaint(taint resourceapi.DeviceTaint, timeout time.Duration) Option {
+	return func(o *options) error {
+		o.shutdownTaint = &taint
+		o.shutdownTaintTimeout = timeout
+		return nil
+	}
+}
+
 // EnableDeviceMetadata enables the device metadata feature. When enabled,
 // the framework writes a metadata file per request under the plugin data
 // directory and a CDI spec per request under the CDI directory (see
@@ -662,6 +681,8 @@ type options struct {
 	enableDeviceMetadata       bool
 	metadataVersions           []schema.GroupVersion
 	cdiDir                     string
+	shutdownTaint              *resourceapi.DeviceTaint
+	shutdownTaintTimeout       time.Duration
 }
 
 // Helper combines the kubelet registration service and the DRA node plugin
@@ -685,6 +706,8 @@ type Helper struct {
 	grpcLockFilePath      string
 	reconcilePoolWithName string
 	metadataWriter        *metadataWriter
+	shutdownTaint         *resourceapi.DeviceTaint
+	shutdownTaintTimeout  time.Duration
 
 	// Information about resource publishing changes concurrently and thus
 	// must be protected by the mutex. The controller gets started only
@@ -760,6 +783,8 @@ func Start(ctx context.Context, plugin DRAPlugin, opts ...Option) (result *Helpe
 		serialize:             o.serialize,
 		plugin:                plugin,
 		reconcilePoolWithName: o.reconcilePoolWithName,
+		shutdownTaint:         o.shutdownTaint,
+		shutdownTaintTimeout:  o.shutdownTaintTimeout,
 	}
 	if o.rollingUpdateUID != "" {
 		dir := o.pluginDataDirectoryPath
@@ -908,10 +933,12 @@ func Start(ctx context.Context, plugin DRAPlugin, opts ...Option) (result *Helpe
 }
 
 // Stop ensures that all spawned goroutines are stopped and frees resources.
+// If [ShutdownTaint] was used, it first taints all published devices.
 func (d *Helper) Stop() {
 	if d == nil {
 		return
 	}
+	d.taintOnShutdown()
 	d.cancel(errors.New("DRA plugin was stopped"))
 	// Wait for goroutines in Start to clean up and exit.
 	d.wg.Wait()
@@ -1004,6 +1031,61 @@ func (d *Helper) PublishResources(_ context.Context, resources resourceslice.Dri
 	return nil
 }
 
+// taintOnShutdown publishes the ShutdownTaint, if there is one
+// and resources were published.
+func (d *Helper) taintOnShutdown() {
+	if d.shutdownTaint == nil || d.backgroundCtx.Err() != nil {
+		// Not enabled or already stopped.
+		return
+	}
+	d.mutex.Lock()
+	controller := d.resourceSliceController
+	d.mutex.Unlock()
+	if controller == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(d.backgroundCtx, d.shutdownTaintTimeout)
+	defer cancel()
+	controller.SetAllDeviceTaints(*d.shutdownTaint)
+	if err := controller.WaitForPublished(ctx); err != nil {
+		d.plugin.HandleError(ctx, recoverableError{error: err}, "publishing the shutdown taint")
+	}
+}
+
+// SetAllDeviceTaints publishes the given taints for all devices in
+// addition to the taints in the resources passed to [Helper.PublishResources].
+// Calling it without taints removes them again. See
+// [resourceslice.Controller.SetAllDeviceTaints] for details.
+//
+// PublishResources must have been called first.
+func (d *Helper) SetAllDeviceTaints(taints ...resourceapi.DeviceTaint) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.resourceSliceController == nil {
+		return errors.New("no resources published yet")
+	}
+	d.resourceSliceController.SetAllDeviceTaints(taints...)
+	return nil
+}
+
+// SetDeviceTaints publishes the given taints for one device, typically
+// because the driver has detected that the device is unhealthy.
+// Calling it without taints removes them again, for example
+// after the device has recovered. See
+// [resourceslice.Controller.SetDeviceTaints] for details.
+//
+// PublishResources must have been called first.
+func (d *Helper) SetDeviceTaints(poolName, deviceName string, taints ...resourceapi.DeviceTaint) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.resourceSliceController == nil {
+		return errors.New("no resources published yet")
+	}
+	d.resourceSliceController.SetDeviceTaints(poolName, deviceName, taints...)
+	return nil
+}
+
 // RegistrationStatus returns the result of registration, nil if none received yet.
 func (d *Helper) RegistrationStatus() *registerapi.RegistrationStatus {
 	if d.registrar == nil {
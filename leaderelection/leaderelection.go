@@ -71,6 +71,10 @@ type leaderElection struct {
 	renewDeadline      time.Duration
 	retryPeriod        time.Duration
 	healthCheckTimeout time.Duration
+	releaseOnCancel    bool
+
+	// onStoppedLeading replaces exiting the process if set.
+	onStoppedLeading func()
 
 	ctx context.Context
 
@@ -136,6 +140,28 @@ func HealthCheckTimeout(timeout time.Duration) Option {
 	}
 }
 
+// ReleaseOnCancel releases the lock when the context gets canceled, so
+// that another member can take over without waiting for the lease to
+// expire. client-go runs the function passed to New in a goroutine and
+// releases the lock without waiting for that function to return. The
+// function therefore must fence its writes itself, for example by
+// checking its context before each write. Returning from it does not
+// protect anything.
+func ReleaseOnCancel(release bool) Option {
+	return func(l *leaderElection) {
+		l.releaseOnCancel = release
+	}
+}
+
+// OnStoppedLeading gets called instead of exiting the process when
+// leadership is lost or the context gets canceled. Run then returns
+// and may be called again to become a candidate again.
+func OnStoppedLeading(onStoppedLeading func()) Option {
+	return func(l *leaderElection) {
+		l.onStoppedLeading = onStoppedLeading
+	}
+}
+
 func Context(ctx context.Context) Option {
 	return func(l *leaderElection) {
 		l.ctx = ctx
@@ -180,6 +206,7 @@ func (l *leaderElection) Run() error {
 
 	broadcaster := record.NewBroadcaster(record.WithContext(ctx))
 	broadcaster.StartRecordingToSink(&corev1.EventSinkImpl{Interface: l.clientset.CoreV1().Events(l.namespace)})
+	defer broadcaster.Shutdown()
 	eventRecorder := broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: fmt.Sprintf("%s/%s", l.lockName, l.identity)})
 
 	rlConfig := resourcelock.ResourceLockConfig{
@@ -203,6 +230,11 @@ func (l *leaderElection) Run() error {
 				l.runFunc(ctx)
 			},
 			OnStoppedLeading: func() {
+				if l.onStoppedLeading != nil {
+					klog.FromContext(ctx).Info("stopped leading")
+					l.onStoppedLeading()
+					return
+				}
 				klog.FromContext(ctx).Error(nil, "stopped leading")
 				klog.FlushAndExit(klog.ExitFlushTimeout, 1)
 			},
@@ -210,11 +242,13 @@ func (l *leaderElection) Run() error {
 				klog.FromContext(ctx).Info("new leader detected", "idendity", identity)
 			},
 		},
-		WatchDog: l.healthCheck,
+		WatchDog:        l.healthCheck,
+		ReleaseOnCancel: l.releaseOnCancel,
 	}
 
 	leaderelection.RunOrDie(ctx, leaderConfig)
-	return nil // should never reach here
+	// Only reached with OnStoppedLeading.
+	return nil
 }
 
 func defaultLeaderElectionIdentity() (string, error) {
@@ -0,0 +1,109 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceslice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+
+	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	metaapply "k8s.io/client-go/applyconfigurations/meta/v1"
+	resourceapply "k8s.io/client-go/applyconfigurations/resource/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+)
+
+// applySliceName replaces the random suffix that the apiserver would add
+// to the generated name with a hash of the pool name. The pool name is
+// needed because the same driver and owner may have more than one pool.
+func applySliceName(generateName, poolName string) string {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(poolName))
+	suffix := fmt.Sprintf("%08x", hash.Sum32())
+	if maxPrefixLength := validation.DNS1123SubdomainMaxLength - len(suffix); len(generateName) > maxPrefixLength {
+		// The index at the beginning is what matters, the rest is
+		// only informational.
+		generateName = generateName[:maxPrefixLength]
+	}
+	return generateName + suffix
+}
+
+// indexerWithoutMutations is used instead of a mutation cache with
+// server-side apply. Results of writes are not needed because the
+// informer cache catches up eventually.
+type indexerWithoutMutations struct {
+	cache.Indexer
+}
+
+var _ cache.MutationCache = indexerWithoutMutations{}
+
+func (indexerWithoutMutations) Mutation(any) {}
+
+// applySlice publishes the slice with server-side apply. Only the
+// fields set by the controller are included, so fields owned by
+// other field managers (for example, labels added by an admin)
+// are left alone.
+func (c *Controller) applySlice(ctx context.Context, slice *resourceapi.ResourceSlice) (*resourceapi.ResourceSlice, error) {
+	spec, err := applySliceSpec(slice.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("ResourceSlice %s: %w", slice.Name, err)
+	}
+	applyConfig := resourceapply.ResourceSlice(slice.Name).
+		WithSpec(spec)
+	if len(c.labels) > 0 {
+		applyConfig.WithLabels(c.labels)
+	}
+	for _, ownerReference := range slice.OwnerReferences {
+		applyConfig.WithOwnerReferences(metaapply.OwnerReference().
+			WithAPIVersion(ownerReference.APIVersion).
+			WithKind(ownerReference.Kind).
+			WithName(ownerReference.Name).
+			WithUID(ownerReference.UID).
+			WithController(ptr.Deref(ownerReference.Controller, false)))
+	}
+	actualSlice, err := c.resourceClient.ResourceSlices().Apply(ctx, applyConfig, metav1.ApplyOptions{
+		FieldManager: c.fieldManager,
+		Force:        false,
+	})
+	if apierrors.IsConflict(err) {
+		klog.FromContext(ctx).V(5).Info("Server-side apply conflict", "slice", klog.KObj(slice), "fieldManager", c.fieldManager, "err", err)
+		return nil, fmt.Errorf("another field manager owns fields of ResourceSlice %s that field manager %q wants to set: %w", slice.Name, c.fieldManager, err)
+	}
+	return actualSlice, err
+}
+
+// applySliceSpec converts the spec into an apply configuration. The
+// controller owns the entire spec and sets all of its fields, so this is
+// equivalent to building the apply configuration field by field. Optional
+// fields which are not set get omitted.
+func applySliceSpec(spec resourceapi.ResourceSliceSpec) (*resourceapply.ResourceSliceSpecApplyConfiguration, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("encode spec: %w", err)
+	}
+	applySpec := &resourceapply.ResourceSliceSpecApplyConfiguration{}
+	if err := json.Unmarshal(data, applySpec); err != nil {
+		return nil, fmt.Errorf("decode spec as apply configuration: %w", err)
+	}
+	return applySpec, nil
+}
@@ -0,0 +1,228 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceslice
+
+import (
+	"fmt"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+)
+
+// ChunkOptions contains the limits used by [ChunkDevices].
+// The zero value uses the API limits.
+type ChunkOptions struct {
+	// MaxDevicesPerSlice defaults to resourceapi.ResourceSliceMaxDevices.
+	MaxDevicesPerSlice int
+	// MaxDeviceCountersPerSlice limits the total number of counters
+	// consumed by the devices in one slice.
+	// Defaults to resourceapi.ResourceSliceMaxDeviceCountersPerSlice.
+	MaxDeviceCountersPerSlice int
+	// MaxSharedCountersPerSlice limits the total number of counters
+	// in the counter sets of one slice.
+	// Defaults to resourceapi.ResourceSliceMaxSharedCounters.
+	MaxSharedCountersPerSlice int
+}
+
+// ChunkDevices splits a flat list of devices and counter sets into slices
+// which stay within the limits of the ResourceSlice API. Counter sets are
+// published in slices without devices.
+//
+// previous is the result of the previous call for the same pool, if
+// there was one. Devices and counter sets which are still present remain in
+// the slice where they were before, so an update of the pool only modifies
+// those ResourceSlices where something actually changed. New devices fill
+// up free space in existing slices before new slices get added.
+// Slices which become empty in the middle are kept as empty placeholders,
+// so the following slices keep their index and don't need to be updated.
+// Trailing empty slices are removed, but there is always at least one
+// slice.
+//
+// Inside each slice, devices and counter sets are in the order of the
+// input lists. Because the allocator tries slices in the order of their
+// index, keeping devices in place means that new devices may end up
+// before older ones.
+//
+// If the devices use per-device node selection, PerDeviceNodeSelection
+// gets set in all slices. Either all devices or none must use it,
+// otherwise some of the slices would be invalid.
+//
+// The result shares data with the input.
+func ChunkDevices(previous []Slice, devices []resourceapi.Device, counterSets []resourceapi.CounterSet, options ChunkOptions) ([]Slice, error) {
+	if options.MaxDevicesPerSlice <= 0 {
+		options.MaxDevicesPerSlice = resourceapi.ResourceSliceMaxDevices
+	}
+	if options.MaxDeviceCountersPerSlice <= 0 {
+		options.MaxDeviceCountersPerSlice = resourceapi.ResourceSliceMaxDeviceCountersPerSlice
+	}
+	if options.MaxSharedCountersPerSlice <= 0 {
+		options.MaxSharedCountersPerSlice = resourceapi.ResourceSliceMaxSharedCounters
+	}
+
+	perDeviceNodeSelection := false
+	deviceNames := sets.New[string]()
+	for i, device := range devices {
+		if deviceNames.Has(device.Name) {
+			return nil, fmt.Errorf("duplicate device %q", device.Name)
+		}
+		deviceNames.Insert(device.Name)
+		if numCounters := deviceCounters(device); numCounters > options.MaxDeviceCountersPerSlice {
+			return nil, fmt.Errorf("device %q consumes %d counters, more than the limit of %d per slice", device.Name, numCounters, options.MaxDeviceCountersPerSlice)
+		}
+		hasNodeSelection := device.NodeName != nil || device.NodeSelector != nil || device.AllNodes != nil
+		switch {
+		case i == 0:
+			perDeviceNodeSelection = hasNodeSelection
+		case hasNodeSelection != perDeviceNodeSelection:
+			return nil, fmt.Errorf("device %q and device %q differ in whether they use per-device node selection, which must be the same for all devices", devices[0].Name, device.Name)
+		}
+	}
+	counterSetNames := sets.New[string]()
+	for _, counterSet := range counterSets {
+		if counterSetNames.Has(counterSet.Name) {
+			return nil, fmt.Errorf("duplicate counter set %q", counterSet.Name)
+		}
+		counterSetNames.Insert(counterSet.Name)
+		if numCounters := len(counterSet.Counters); numCounters > options.MaxSharedCountersPerSlice {
+			return nil, fmt.Errorf("counter set %q has %d counters, more than the limit of %d per slice", counterSet.Name, numCounters, options.MaxSharedCountersPerSlice)
+		}
+	}
+
+	// Start with the same layout as before, without content.
+	chunks := make([]chunk, len(previous))
+	previousDeviceChunk := make(map[string]int)
+	previousCounterSetChunk := make(map[string]int)
+	for i, slice := range previous {
+		for _, device := range slice.Devices {
+			previousDeviceChunk[device.Name] = i
+		}
+		for _, counterSet := range slice.SharedCounters {
+			previousCounterSetChunk[counterSet.Name] = i
+		}
+	}
+
+	// Keep counter sets and devices in their previous slice if they still fit there.
+	var pendingCounterSets, pendingDevices []int
+	for i, counterSet := range counterSets {
+		if index, ok := previousCounterSetChunk[counterSet.Name]; ok && chunks[index].fitsCounterSet(counterSet, options) {
+			chunks[index].addCounterSet(i, counterSet)
+			continue
+		}
+		pendingCounterSets = append(pendingCounterSets, i)
+	}
+	for i, device := range devices {
+		if index, ok := previousDeviceChunk[device.Name]; ok && chunks[index].fitsDevice(device, options) {
+			chunks[index].addDevice(i, device)
+			continue
+		}
+		pendingDevices = append(pendingDevices, i)
+	}
+
+	// Everything else goes into the first slice with enough space or into a new slice.
+	for _, i := range pendingCounterSets {
+		index := firstFit(&chunks, func(c *chunk) bool { return c.fitsCounterSet(counterSets[i], options) })
+		chunks[index].addCounterSet(i, counterSets[i])
+	}
+	for _, i := range pendingDevices {
+		index := firstFit(&chunks, func(c *chunk) bool { return c.fitsDevice(devices[i], options) })
+		chunks[index].addDevice(i, devices[i])
+	}
+
+	for len(chunks) > 1 && chunks[len(chunks)-1].isEmpty() {
+		chunks = chunks[:len(chunks)-1]
+	}
+	if len(chunks) == 0 {
+		chunks = append(chunks, chunk{})
+	}
+
+	result := make([]Slice, len(chunks))
+	for i, c := range chunks {
+		// The indices were added in two passes, so they need to be sorted
+		// to get the same order as in the input.
+		for _, index := range sets.List(c.counterSets) {
+			result[i].SharedCounters = append(result[i].SharedCounters, counterSets[index])
+		}
+		for _, index := range sets.List(c.devices) {
+			result[i].Devices = append(result[i].Devices, devices[index])
+		}
+		if perDeviceNodeSelection {
+			result[i].PerDeviceNodeSelection = ptr.To(true)
+		}
+	}
+	return result, nil
+}
+
+// chunk tracks the content of one slice in [ChunkDevices]. A chunk contains
+// either devices or counter sets, never both.
+type chunk struct {
+	devices           sets.Set[int]
+	counterSets       sets.Set[int]
+	numDeviceCounters int
+	numSharedCounters int
+}
+
+func (c *chunk) isEmpty() bool {
+	return c.devices.Len() == 0 && c.counterSets.Len() == 0
+}
+
+func (c *chunk) fitsDevice(device resourceapi.Device, options ChunkOptions) bool {
+	return c.counterSets.Len() == 0 &&
+		c.devices.Len() < options.MaxDevicesPerSlice &&
+		c.numDeviceCounters+deviceCounters(device) <= options.MaxDeviceCountersPerSlice
+}
+
+func (c *chunk) fitsCounterSet(counterSet resourceapi.CounterSet, options ChunkOptions) bool {
+	return c.devices.Len() == 0 &&
+		c.numSharedCounters+len(counterSet.Counters) <= options.MaxSharedCountersPerSlice
+}
+
+func (c *chunk) addDevice(index int, device resourceapi.Device) {
+	if c.devices == nil {
+		c.devices = sets.New[int]()
+	}
+	c.devices.Insert(index)
+	c.numDeviceCounters += deviceCounters(device)
+}
+
+func (c *chunk) addCounterSet(index int, counterSet resourceapi.CounterSet) {
+	if c.counterSets == nil {
+		c.counterSets = sets.New[int]()
+	}
+	c.counterSets.Insert(index)
+	c.numSharedCounters += len(counterSet.Counters)
+}
+
+// firstFit returns the index of the first chunk for which fits returns true.
+// A new chunk gets appended if none fits.
+func firstFit(chunks *[]chunk, fits func(c *chunk) bool) int {
+	for i := range *chunks {
+		if fits(&(*chunks)[i]) {
+			return i
+		}
+	}
+	*chunks = append(*chunks, chunk{})
+	return len(*chunks) - 1
+}
+
+func deviceCounters(device resourceapi.Device) int {
+	numCounters := 0
+	for _, consumption := range device.ConsumesCounters {
+		numCounters += len(consumption.Counters)
+	}
+	return numCounters
+}
@@ -0,0 +1,179 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceslice
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/ptr"
+)
+
+func TestChunkDevices(t *testing.T) {
+	device := func(name string, consumesCounters ...string) resourceapi.Device {
+		d := resourceapi.Device{Name: name}
+		for _, counterSet := range consumesCounters {
+			d.ConsumesCounters = append(d.ConsumesCounters, resourceapi.DeviceCounterConsumption{
+				CounterSet: counterSet,
+				Counters:   map[string]resourceapi.Counter{"memory": {Value: resource.MustParse("1Gi")}},
+			})
+		}
+		return d
+	}
+	counterSet := func(name string, numCounters int) resourceapi.CounterSet {
+		cs := resourceapi.CounterSet{Name: name, Counters: map[string]resourceapi.Counter{}}
+		for i := 0; i < numCounters; i++ {
+			cs.Counters[fmt.Sprintf("counter-%d", i)] = resourceapi.Counter{Value: resource.MustParse("1")}
+		}
+		return cs
+	}
+	names := func(slices []Slice) [][]string {
+		var result [][]string
+		for _, slice := range slices {
+			var sliceNames []string
+			for _, counterSet := range slice.SharedCounters {
+				sliceNames = append(sliceNames, "cs:"+counterSet.Name)
+			}
+			for _, device := range slice.Devices {
+				sliceNames = append(sliceNames, device.Name)
+			}
+			result = append(result, sliceNames)
+		}
+		return result
+	}
+	limits := ChunkOptions{MaxDevicesPerSlice: 2, MaxDeviceCountersPerSlice: 3, MaxSharedCountersPerSlice: 4}
+
+	testcases := map[string]struct {
+		previous    []Slice
+		devices     []resourceapi.Device
+		counterSets []resourceapi.CounterSet
+		options     ChunkOptions
+		expected    [][]string
+		expectError string
+	}{
+		"empty": {
+			expected: [][]string{nil},
+		},
+		"default-limits": {
+			devices:  []resourceapi.Device{device("a"), device("b"), device("c")},
+			expected: [][]string{{"a", "b", "c"}},
+		},
+		"split": {
+			devices:  []resourceapi.Device{device("a"), device("b"), device("c")},
+			options:  limits,
+			expected: [][]string{{"a", "b"}, {"c"}},
+		},
+		"counter-sets-separate": {
+			devices:     []resourceapi.Device{device("a", "cs1"), device("b", "cs2")},
+			counterSets: []resourceapi.CounterSet{counterSet("cs1", 3), counterSet("cs2", 3)},
+			options:     limits,
+			expected:    [][]string{{"cs:cs1"}, {"cs:cs2"}, {"a", "b"}},
+		},
+		"device-counter-limit": {
+			devices:     []resourceapi.Device{device("a", "cs1", "cs1"), device("b", "cs1", "cs1")},
+			counterSets: []resourceapi.CounterSet{counterSet("cs1", 1)},
+			options:     limits,
+			expected:    [][]string{{"cs:cs1"}, {"a"}, {"b"}},
+		},
+		"stable": {
+			previous: []Slice{
+				{Devices: []resourceapi.Device{device("a"), device("b")}},
+				{Devices: []resourceapi.Device{device("c"), device("d")}},
+			},
+			// "a" gets removed, "e" is new.
+			devices:  []resourceapi.Device{device("e"), device("d"), device("c"), device("b")},
+			options:  limits,
+			expected: [][]string{{"e", "b"}, {"d", "c"}},
+		},
+		"stable-counter-sets": {
+			previous: []Slice{
+				{SharedCounters: []resourceapi.CounterSet{counterSet("cs1", 1)}},
+				{Devices: []resourceapi.Device{device("a", "cs1")}},
+			},
+			devices:     []resourceapi.Device{device("a", "cs1"), device("b", "cs1")},
+			counterSets: []resourceapi.CounterSet{counterSet("cs1", 1)},
+			options:     limits,
+			expected:    [][]string{{"cs:cs1"}, {"a", "b"}},
+		},
+		"trim": {
+			previous: []Slice{
+				{Devices: []resourceapi.Device{device("a"), device("b")}},
+				{Devices: []resourceapi.Device{device("c"), device("d")}},
+				{Devices: []resourceapi.Device{device("e")}},
+			},
+			devices:  []resourceapi.Device{device("a"), device("b"), device("e")},
+			options:  limits,
+			expected: [][]string{{"a", "b"}, nil, {"e"}},
+		},
+		"trim-trailing": {
+			previous: []Slice{
+				{Devices: []resourceapi.Device{device("a"), device("b")}},
+				{Devices: []resourceapi.Device{device("c"), device("d")}},
+			},
+			devices:  []resourceapi.Device{device("a")},
+			options:  limits,
+			expected: [][]string{{"a"}},
+		},
+		"duplicate-device": {
+			devices:     []resourceapi.Device{device("a"), device("a")},
+			expectError: `duplicate device "a"`,
+		},
+		"duplicate-counter-set": {
+			counterSets: []resourceapi.CounterSet{counterSet("cs1", 1), counterSet("cs1", 1)},
+			expectError: `duplicate counter set "cs1"`,
+		},
+		"counter-set-too-large": {
+			counterSets: []resourceapi.CounterSet{counterSet("cs1", 5)},
+			options:     limits,
+			expectError: `counter set "cs1" has 5 counters, more than the limit of 4 per slice`,
+		},
+		"device-too-large": {
+			devices:     []resourceapi.Device{device("a", "cs1", "cs1", "cs1", "cs1")},
+			options:     limits,
+			expectError: `device "a" consumes 4 counters, more than the limit of 3 per slice`,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			slices, err := ChunkDevices(tc.previous, tc.devices, tc.counterSets, tc.options)
+			if tc.expectError != "" {
+				require.EqualError(t, err, tc.expectError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, names(slices))
+		})
+	}
+}
+
+func TestChunkDevicesPerDeviceNodeSelection(t *testing.T) {
+	slices, err := ChunkDevices(nil, []resourceapi.Device{{Name: "a", NodeName: ptr.To("node-a")}, {Name: "b", AllNodes: ptr.To(true)}}, nil, ChunkOptions{MaxDevicesPerSlice: 1})
+	require.NoError(t, err)
+	require.Len(t, slices, 2)
+	for _, slice := range slices {
+		assert.Equal(t, ptr.To(true), slice.PerDeviceNodeSelection)
+	}
+
+	_, err = ChunkDevices(nil, []resourceapi.Device{{Name: "a", NodeName: ptr.To("node-a")}, {Name: "b"}}, nil, ChunkOptions{})
+	require.EqualError(t, err, `device "a" and device "b" differ in whether they use per-device node selection, which must be the same for all devices`)
+}
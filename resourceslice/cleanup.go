@@ -0,0 +1,95 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceslice
+
+import (
+	"context"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// DefaultOrphanRecheckInterval is how often an [OrphanDetector] gets
+// asked again about a pool that it did not consider orphaned.
+const DefaultOrphanRecheckInterval = 5 * time.Minute
+
+// CleanupStrategy decides what happens with ResourceSlices which are
+// seen by the controller but belong to a pool that is not part of the
+// desired state.
+//
+// The default is to delete them. This is correct when the controller
+// is the only one which publishes ResourceSlices for the driver and
+// owner, or when it only watches its own ResourceSlices because of
+// [Options.Labels].
+type CleanupStrategy interface {
+	// ShouldDelete gets called for a pool which has ResourceSlices
+	// but is not part of the desired state. If it returns false, the
+	// ResourceSlices are kept and the pool gets checked again after
+	// the returned duration. Zero means that it only gets checked
+	// again when its ResourceSlices change. An error gets reported
+	// and the check gets retried.
+	ShouldDelete(ctx context.Context, driverName, poolName string, slices []*resourceapi.ResourceSlice) (bool, time.Duration, error)
+}
+
+// OrphanDetector is a [CleanupStrategy] for drivers where several
+// controllers publish different pools without a common owner, for
+// example controllers managing network-attached devices. Unknown
+// pools only get deleted if IsOrphaned returns true for them.
+type OrphanDetector struct {
+	// IsOrphaned determines whether some other instance is
+	// responsible for the pool. Must be set.
+	IsOrphaned func(ctx context.Context, driverName, poolName string) (bool, error)
+
+	// RecheckInterval defaults to [DefaultOrphanRecheckInterval].
+	RecheckInterval time.Duration
+}
+
+var _ CleanupStrategy = OrphanDetector{}
+
+func (d OrphanDetector) ShouldDelete(ctx context.Context, driverName, poolName string, slices []*resourceapi.ResourceSlice) (bool, time.Duration, error) {
+	orphaned, err := d.IsOrphaned(ctx, driverName, poolName)
+	if err != nil || orphaned {
+		return orphaned, 0, err
+	}
+	interval := d.RecheckInterval
+	if interval <= 0 {
+		interval = DefaultOrphanRecheckInterval
+	}
+	return false, interval, nil
+}
+
+// deleteUnknownPools is the default CleanupStrategy.
+type deleteUnknownPools struct{}
+
+func (deleteUnknownPools) ShouldDelete(ctx context.Context, driverName, poolName string, slices []*resourceapi.ResourceSlice) (bool, time.Duration, error) {
+	return true, 0, nil
+}
+
+// sweep enqueues all pools which have ResourceSlices in the informer cache
+// and are not part of the desired state, without the usual sync delay.
+// The controller then deletes the ResourceSlices left behind by some
+// previous instance.
+func (c *Controller) sweep(indexer cache.Indexer) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, poolName := range indexer.ListIndexFuncValues(poolNameIndex) {
+		if _, ok := c.resources.Pools[poolName]; !ok {
+			c.queue.Add(poolName)
+		}
+	}
+}
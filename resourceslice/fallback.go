@@ -0,0 +1,227 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceslice
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/ptr"
+)
+
+// FallbackPolicy enables publishing a degraded variant of the desired
+// ResourceSlices when the apiserver drops fields because a feature is
+// disabled in the cluster. Without a policy, the controller publishes
+// whatever the apiserver accepts and only reports a [DroppedFieldsError].
+//
+// Once a feature is detected as disabled, all pools get published
+// without the fields which depend on it until the controller gets
+// restarted. This is reported once per feature through the ErrorHandler
+// in [Options] with a [FallbackError].
+type FallbackPolicy struct {
+	// DropTaints removes all device taints if DRADeviceTaints
+	// is disabled.
+	DropTaints bool
+
+	// FlattenPartitionableDevices removes counter sets and counter
+	// consumption if DRAPartitionableDevices is disabled. Devices which
+	// consume counters are kept in the order in which they are listed
+	// in the pool as long as they don't consume counters that are
+	// already consumed by the devices kept before them. The result are
+	// whole devices which cannot be allocated at the same time as
+	// another device with overlapping resources. Drivers should list
+	// the larger devices first.
+	//
+	// Devices which depend on per-device node selection get removed
+	// because their node selection cannot be described without the
+	// feature. Their slices then use the node selection of the pool.
+	// Pools which have no node selection of their own (no NodeSelector,
+	// no AllNodes and no node owner) cannot be published this way and
+	// fail permanently with an error.
+	FlattenPartitionableDevices bool
+
+	// DropBindingConditions removes binding conditions if
+	// DRADeviceBindingConditions is disabled. Such devices
+	// then get used without waiting for the conditions.
+	DropBindingConditions bool
+}
+
+// FallbackError is reported through the ErrorHandler in [Options]
+// instead of a [DroppedFieldsError] when the [FallbackPolicy] was
+// applied because of it. All pools then get published again
+// without the fields which depend on the disabled features.
+type FallbackError struct {
+	*DroppedFieldsError
+
+	// Features lists the features which were newly detected
+	// as disabled.
+	Features []string
+}
+
+func (err *FallbackError) Error() string {
+	return fmt.Sprintf("%s; publishing all pools again without fields for %s", err.DroppedFieldsError.Error(), strings.Join(err.Features, " "))
+}
+
+func (err *FallbackError) Unwrap() error {
+	return err.DroppedFieldsError
+}
+
+var _ error = &FallbackError{}
+
+// supports returns true if the policy can handle the disabled feature.
+func (p *FallbackPolicy) supports(feature string) bool {
+	switch feature {
+	case "DRADeviceTaints":
+		return p.DropTaints
+	case "DRAPartitionableDevices":
+		return p.FlattenPartitionableDevices
+	case "DRADeviceBindingConditions":
+		return p.DropBindingConditions
+	default:
+		return false
+	}
+}
+
+// DegradedFeatures returns the features for which the controller
+// currently removes fields because of the [FallbackPolicy].
+func (c *Controller) DegradedFeatures() []string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return sets.List(c.degradedFeatures)
+}
+
+// degrade records the disabled features that the policy supports
+// and triggers a sync of all pools if there are new ones. It returns
+// those new features.
+func (c *Controller) degrade(disabled []string) []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var features []string
+	for _, feature := range disabled {
+		if c.fallback.supports(feature) && !c.degradedFeatures.Has(feature) {
+			features = append(features, feature)
+		}
+	}
+	if len(features) == 0 {
+		return nil
+	}
+	// syncPool reads the set without holding the mutex,
+	// so it must be replaced instead of modified in place.
+	c.degradedFeatures = c.degradedFeatures.Clone().Insert(features...)
+	if c.resources != nil {
+		for poolName := range c.resources.Pools {
+			c.poolChanged(poolName)
+		}
+	}
+	return features
+}
+
+// degradePool returns the pool without the fields which depend on the
+// degraded features. Slices and devices which get modified are copied,
+// everything else is shared with the input. nodeName is the node name
+// which gets set in the slices, if any.
+//
+// It fails if the result would be slices without any node selection.
+// Retrying doesn't help in that case.
+func degradePool(pool Pool, features sets.Set[string], nodeName string) (Pool, error) {
+	if features.Len() == 0 {
+		return pool, nil
+	}
+	flatten := features.Has("DRAPartitionableDevices")
+	if flatten && pool.NodeSelector == nil && !pool.AllNodes && nodeName == "" {
+		for i, slice := range pool.Slices {
+			if ptr.Deref(slice.PerDeviceNodeSelection, false) {
+				return Pool{}, fmt.Errorf("slice #%d uses per-device node selection and the pool has no node selection which could be used instead without DRAPartitionableDevices", i)
+			}
+		}
+	}
+	var available map[string]map[string]resource.Quantity
+	if flatten {
+		available = make(map[string]map[string]resource.Quantity)
+		for _, slice := range pool.Slices {
+			for _, counterSet := range slice.SharedCounters {
+				counters := make(map[string]resource.Quantity, len(counterSet.Counters))
+				for name, counter := range counterSet.Counters {
+					counters[name] = counter.Value.DeepCopy()
+				}
+				available[counterSet.Name] = counters
+			}
+		}
+	}
+
+	pool.Slices = slices.Clone(pool.Slices)
+	for i := range pool.Slices {
+		slice := &pool.Slices[i]
+		devices := make([]resourceapi.Device, 0, len(slice.Devices))
+		for _, device := range slice.Devices {
+			if flatten {
+				if device.NodeName != nil || device.NodeSelector != nil || device.AllNodes != nil ||
+					!consumeCounters(available, device.ConsumesCounters) {
+					continue
+				}
+				device.ConsumesCounters = nil
+			}
+			if features.Has("DRADeviceTaints") {
+				device.Taints = nil
+			}
+			if features.Has("DRADeviceBindingConditions") {
+				device.BindingConditions = nil
+				device.BindingFailureConditions = nil
+				device.BindsToNode = nil
+			}
+			devices = append(devices, device)
+		}
+		slice.Devices = devices
+		if flatten {
+			slice.SharedCounters = nil
+			slice.PerDeviceNodeSelection = nil
+		}
+	}
+	return pool, nil
+}
+
+// consumeCounters subtracts the consumed counters from the available ones
+// if all of them are available. It returns false without modifying
+// anything otherwise.
+func consumeCounters(available map[string]map[string]resource.Quantity, consumption []resourceapi.DeviceCounterConsumption) bool {
+	for _, consumed := range consumption {
+		counters, ok := available[consumed.CounterSet]
+		if !ok {
+			return false
+		}
+		for name, counter := range consumed.Counters {
+			value, ok := counters[name]
+			if !ok || value.Cmp(counter.Value) < 0 {
+				return false
+			}
+		}
+	}
+	for _, consumed := range consumption {
+		counters := available[consumed.CounterSet]
+		for name, counter := range consumed.Counters {
+			value := counters[name]
+			value.Sub(counter.Value)
+			counters[name] = value
+		}
+	}
+	return true
+}
@@ -0,0 +1,199 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceslice
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+)
+
+// GenerationStore persists the most recent generation of each pool.
+//
+// Without it, the controller derives the next generation from the
+// ResourceSlices that it finds when syncing a pool. That information
+// is lost when all ResourceSlices of a pool get removed and might be
+// outdated while the informer cache catches up after a restart.
+// Starting again with a generation that was used before can cause
+// consumers with an older view of the pool to consider the new
+// ResourceSlices as outdated.
+type GenerationStore interface {
+	// Generation returns the stored generation of the pool,
+	// zero if unknown.
+	Generation(ctx context.Context, poolName string) (int64, error)
+
+	// SetGeneration stores the generation of the pool after
+	// it was published.
+	SetGeneration(ctx context.Context, poolName string, generation int64) error
+}
+
+// ConfigMapGenerationStore is a [GenerationStore] which stores
+// generations in a ConfigMap, with one key per pool. The
+// ConfigMap gets created if it does not exist yet.
+//
+// Each update writes the entire ConfigMap. Controllers which share
+// the same ConfigMap, like the instances of a DaemonSet which publish
+// one pool per node, therefore keep running into update conflicts.
+// Each of them should use its own ConfigMap, which is what
+// [NewConfigMapGenerationStore] does by default.
+type ConfigMapGenerationStore struct {
+	KubeClient kubernetes.Interface
+	Namespace  string
+	Name       string
+}
+
+var _ GenerationStore = ConfigMapGenerationStore{}
+
+// NewConfigMapGenerationStore returns a store which uses a ConfigMap
+// in the namespace with a name that is derived from the driver name and
+// the owner of the ResourceSlices, for example
+// "gpu.example.com-node-worker-1-generations" for a Node owner. The owner
+// is the same as in [Options.Owner] and may be nil.
+func NewConfigMapGenerationStore(kubeClient kubernetes.Interface, namespace, driverName string, owner *Owner) ConfigMapGenerationStore {
+	return ConfigMapGenerationStore{
+		KubeClient: kubeClient,
+		Namespace:  namespace,
+		Name:       generationConfigMapName(driverName, owner),
+	}
+}
+
+// generationConfigMapName returns the default ConfigMap name. Driver names
+// and owner names are DNS subdomains, so only the kind needs to be converted.
+// Names which are too long get shortened with a hash.
+func generationConfigMapName(driverName string, owner *Owner) string {
+	name := driverName
+	if owner != nil {
+		name += "-" + strings.ToLower(owner.Kind) + "-" + owner.Name
+	}
+	name += "-generations"
+	if len(name) > validation.DNS1123SubdomainMaxLength {
+		hash := sha256.Sum256([]byte(name))
+		suffix := "-" + hex.EncodeToString(hash[:8])
+		name = strings.TrimRight(name[:validation.DNS1123SubdomainMaxLength-len(suffix)], ".-") + suffix
+	}
+	return name
+}
+
+// configMapKey turns a pool name, which may contain slashes, into a valid
+// ConfigMap key. Invalid characters get replaced and a hash of the pool name
+// avoids conflicts between pool names which only differ in those characters.
+func configMapKey(poolName string) string {
+	hash := sha256.Sum256([]byte(poolName))
+	suffix := "." + hex.EncodeToString(hash[:8])
+	key := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, poolName)
+	if maxLen := validation.DNS1123SubdomainMaxLength - len(suffix); len(key) > maxLen {
+		key = key[:maxLen]
+	}
+	return key + suffix
+}
+
+func (s ConfigMapGenerationStore) Generation(ctx context.Context, poolName string) (int64, error) {
+	configMap, err := s.KubeClient.CoreV1().ConfigMaps(s.Namespace).Get(ctx, s.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get ConfigMap %s/%s: %w", s.Namespace, s.Name, err)
+	}
+	value, ok := configMap.Data[configMapKey(poolName)]
+	if !ok {
+		return 0, nil
+	}
+	generation, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("ConfigMap %s/%s: parse generation of pool %q: %w", s.Namespace, s.Name, poolName, err)
+	}
+	return generation, nil
+}
+
+func (s ConfigMapGenerationStore) SetGeneration(ctx context.Context, poolName string, generation int64) error {
+	client := s.KubeClient.CoreV1().ConfigMaps(s.Namespace)
+	configMap, err := client.Get(ctx, s.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		configMap = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: s.Namespace,
+				Name:      s.Name,
+			},
+			Data: map[string]string{configMapKey(poolName): strconv.FormatInt(generation, 10)},
+		}
+		_, err = client.Create(ctx, configMap, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("create ConfigMap %s/%s: %w", s.Namespace, s.Name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get ConfigMap %s/%s: %w", s.Namespace, s.Name, err)
+	}
+	configMap = configMap.DeepCopy()
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[configMapKey(poolName)] = strconv.FormatInt(generation, 10)
+	// A conflict gets reported and the controller tries again.
+	if _, err := client.Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("update ConfigMap %s/%s: %w", s.Namespace, s.Name, err)
+	}
+	return nil
+}
+
+// storedGeneration returns the most recent generation that the
+// controller published for the pool, loading it from the
+// GenerationStore if necessary. Only called by the worker,
+// so no locking needed.
+func (c *Controller) storedGeneration(ctx context.Context, poolName string) (int64, error) {
+	if generation, ok := c.generations[poolName]; ok || c.generationStore == nil {
+		return generation, nil
+	}
+	generation, err := c.generationStore.Generation(ctx, poolName)
+	if err != nil {
+		return 0, fmt.Errorf("load pool generation: %w", err)
+	}
+	c.generations[poolName] = generation
+	return generation, nil
+}
+
+// generationPublished remembers the generation after publishing the pool.
+func (c *Controller) generationPublished(ctx context.Context, poolName string, generation int64) error {
+	if c.generations[poolName] >= generation {
+		return nil
+	}
+	if c.generationStore != nil {
+		if err := c.generationStore.SetGeneration(ctx, poolName, generation); err != nil {
+			return fmt.Errorf("store pool generation: %w", err)
+		}
+	}
+	c.generations[poolName] = generation
+	return nil
+}
@@ -0,0 +1,242 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceslice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/dynamic-resource-allocation/leaderelection"
+	"k8s.io/klog/v2"
+)
+
+// DefaultLeaderElectionRetryDelay is how long a [LeaderElectedController]
+// waits before becoming a candidate again after leader election failed.
+const DefaultLeaderElectionRetryDelay = 5 * time.Second
+
+// LeaderElectedController runs a [Controller] only while holding the
+// leader election lock. This is meant for a central deployment with
+// several replicas which publishes ResourceSlices for network-attached
+// devices.
+//
+// The desired state is remembered while not being the leader and
+// gets published as soon as the instance becomes the leader.
+type LeaderElectedController struct {
+	cancel       func(cause error)
+	wg           sync.WaitGroup
+	options      Options
+	errorHandler func(ctx context.Context, err error, msg string)
+
+	mutex sync.Mutex
+	// resources is the most recent desired state. Like in the
+	// Controller, it gets replaced instead of modified.
+	resources *DriverResources
+	// controller is non-nil while being the leader.
+	controller *Controller
+}
+
+// StartLeaderElectedController participates in leader election with
+// [leaderelection.New] and the given lock name and options. While being
+// the leader, it runs a controller with the given options. The instance
+// keeps being a candidate after losing leadership until it gets
+// stopped. Stopping it releases the lock, so another instance can take
+// over immediately.
+//
+// Writes are fenced: once leadership is lost, the controller stops
+// creating, updating or deleting ResourceSlices and pending API calls
+// get canceled. Leadership is lost when the lease cannot be renewed
+// within the renew deadline, which is shorter than the lease duration
+// after which another instance can become the leader.
+//
+// After a failover, the new leader adopts the ResourceSlices published
+// by the previous one and only updates them where they differ from its
+// desired state. This requires that all instances use the same Owner,
+// Labels, SliceNamer and (with server-side apply) FieldManager. The
+// Owner therefore must not be the Pod of an instance. Without server-side
+// apply, ResourceSlices which were created by the old leader shortly
+// before the failover might not be in the informer cache of the new
+// leader yet. The new leader then creates them again and deletes the
+// duplicates after the SyncDelay.
+//
+// If the controller cannot be created after becoming the leader, then
+// the error is reported, leadership is given up and the instance becomes
+// a candidate again after the DefaultLeaderElectionRetryDelay.
+//
+// Options.Resources is the initial desired state. The leader election
+// options must not include [leaderelection.Context],
+// [leaderelection.ReleaseOnCancel] or [leaderelection.OnStoppedLeading].
+func StartLeaderElectedController(ctx context.Context, options Options, lockName string, opts ...leaderelection.Option) (*LeaderElectedController, error) {
+	if options.KubeClient == nil {
+		return nil, errors.New("KubeClient is nil")
+	}
+	if options.DriverName == "" {
+		return nil, errors.New("DRA driver name is empty")
+	}
+	if options.Queue != nil {
+		// A queue cannot be reused after shutting down.
+		return nil, errors.New("a custom Queue is not supported with leader election")
+	}
+	if options.DryRun {
+		return nil, errors.New("DryRun is not supported with leader election")
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	l := &LeaderElectedController{
+		cancel:       cancel,
+		options:      options,
+		errorHandler: options.ErrorHandler,
+		resources:    options.Resources.DeepCopy(),
+	}
+	if l.errorHandler == nil {
+		l.errorHandler = func(ctx context.Context, err error, msg string) {
+			utilruntime.HandleErrorWithContext(ctx, err, msg)
+		}
+	}
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		logger := klog.FromContext(ctx)
+		wait.UntilWithContext(ctx, func(ctx context.Context) {
+			// Canceling the context of one round releases the
+			// lock, which is how leadership gets given up.
+			ctx, resign := context.WithCancelCause(ctx)
+			defer resign(nil)
+			electionOpts := append(slices.Clone(opts),
+				leaderelection.Context(ctx),
+				leaderelection.ReleaseOnCancel(true),
+				leaderelection.OnStoppedLeading(l.stopController),
+			)
+			election := leaderelection.New(options.KubeClient, lockName, func(ctx context.Context) {
+				l.lead(ctx, resign)
+			}, electionOpts...)
+			if err := election.Run(); err != nil {
+				l.errorHandler(ctx, err, "leader election")
+				return
+			}
+			logger.V(3).Info("Leader election ended, becoming a candidate again")
+		}, DefaultLeaderElectionRetryDelay)
+	}()
+	return l, nil
+}
+
+// lead gets called by leader election after becoming the leader.
+// The context gets canceled when leadership is lost. Calling resign
+// gives up leadership.
+func (l *LeaderElectedController) lead(ctx context.Context, resign func(cause error)) {
+	logger := klog.FromContext(ctx)
+
+	// Creating the controller waits for the informer cache,
+	// so don't block Update while doing that.
+	l.mutex.Lock()
+	options := l.options
+	options.Resources = l.resources
+	l.mutex.Unlock()
+	c, err := newController(ctx, options)
+	if err != nil {
+		// Holding the lock without publishing anything would
+		// prevent other instances from taking over.
+		err = fmt.Errorf("create controller: %w", err)
+		l.errorHandler(ctx, err, "leader election")
+		resign(err)
+		return
+	}
+	c.fence = func() error {
+		if ctx.Err() != nil {
+			return fmt.Errorf("not the leader anymore: %w", context.Cause(ctx))
+		}
+		return nil
+	}
+
+	l.mutex.Lock()
+	if ctx.Err() != nil {
+		// Leadership was lost already and stopController
+		// might have been called.
+		l.mutex.Unlock()
+		c.Stop()
+		return
+	}
+	if l.resources != options.Resources {
+		c.Update(l.resources)
+	}
+	l.controller = c
+	l.mutex.Unlock()
+
+	logger.V(3).Info("Became the leader, publishing ResourceSlices")
+	c.start(ctx)
+}
+
+// stopController gets called by leader election after losing
+// leadership or when the LeaderElectedController gets stopped.
+func (l *LeaderElectedController) stopController() {
+	l.mutex.Lock()
+	c := l.controller
+	l.controller = nil
+	l.mutex.Unlock()
+
+	c.Stop()
+}
+
+// Stop cancels all background activity, releases the leader election
+// lock and blocks until everything has stopped.
+func (l *LeaderElectedController) Stop() {
+	if l == nil {
+		return
+	}
+	l.cancel(errors.New("leader-elected ResourceSlice controller was asked to stop"))
+	l.wg.Wait()
+	// In case that leader election did not call OnStoppedLeading.
+	l.stopController()
+}
+
+// Update sets the new desired state of the resource information,
+// like [Controller.Update]. It is remembered while not being the
+// leader.
+func (l *LeaderElectedController) Update(resources *DriverResources) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.resources = resources.DeepCopy()
+	if l.controller != nil {
+		l.controller.Update(l.resources)
+	}
+}
+
+// IsLeader returns true while the instance is publishing ResourceSlices.
+func (l *LeaderElectedController) IsLeader() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.controller != nil
+}
+
+// Controller returns the controller which is running while being the
+// leader, nil otherwise. It can be used to check the status of the
+// ResourceSlices with [Controller.WaitForPublished]. The returned
+// controller gets stopped when leadership is lost.
+func (l *LeaderElectedController) Controller() *Controller {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.controller
+}
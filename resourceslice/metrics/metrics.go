@@ -0,0 +1,176 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"sync"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+// subsystem is intentionally generic because these metrics are exposed by different DRA drivers.
+const subsystem = "dynamic_resource_allocation"
+
+var (
+	// ResourceSliceOperations tracks the total number of
+	// ResourceSlice API calls made by the ResourceSlice controller,
+	// categorized by driver, operation (create, update, delete)
+	// and status (success, failure).
+	ResourceSliceOperations = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      subsystem,
+			Name:           "resourceslice_controller_operations_total",
+			Help:           "Number of ResourceSlice API calls made by the ResourceSlice controller, categorized by driver, operation and status",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"driver", "operation", "status"},
+	)
+
+	// ResourceSliceSyncDuration tracks how long it takes to synchronize
+	// the ResourceSlices of one pool. There is no pool label because
+	// drivers with one pool per node would have too many of them.
+	ResourceSliceSyncDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      subsystem,
+			Name:           "resourceslice_controller_sync_duration_seconds",
+			Help:           "Duration of synchronizing the ResourceSlices of one pool, categorized by driver and status",
+			Buckets:        metrics.ExponentialBuckets(0.001, 2, 15),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"driver", "status"},
+	)
+
+	// ResourceSliceQueueDepth is the number of pools waiting to be
+	// synchronized.
+	ResourceSliceQueueDepth = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      subsystem,
+			Name:           "resourceslice_controller_queue_depth",
+			Help:           "Number of pools waiting to be synchronized by the ResourceSlice controller, categorized by driver",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"driver"},
+	)
+
+	// ResourceSliceSyncRetries tracks how often the synchronization
+	// of a pool failed and had to be retried.
+	ResourceSliceSyncRetries = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      subsystem,
+			Name:           "resourceslice_controller_sync_retries_total",
+			Help:           "Number of times that synchronizing a pool failed and got retried, categorized by driver",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"driver"},
+	)
+
+	// ResourceSliceDroppedFields tracks how often the API server dropped
+	// fields from a ResourceSlice, categorized by the feature which is
+	// probably disabled.
+	ResourceSliceDroppedFields = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      subsystem,
+			Name:           "resourceslice_controller_dropped_fields_total",
+			Help:           "Number of times that the API server dropped fields from a ResourceSlice, categorized by driver and disabled feature",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"driver", "feature"},
+	)
+
+	// ResourceSlicePublishedDevices is the number of devices
+	// in the published ResourceSlices of all pools. There is no
+	// pool label for the same reason as in ResourceSliceSyncDuration.
+	ResourceSlicePublishedDevices = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      subsystem,
+			Name:           "resourceslice_controller_published_devices",
+			Help:           "Number of devices in the published ResourceSlices, categorized by driver",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"driver"},
+	)
+
+	// ResourceSliceTrackerEventQueueLength is the number of events
+	// which the ResourceSlice tracker has not delivered to its event
+	// handlers yet. A growing queue indicates that handlers are too slow.
+	ResourceSliceTrackerEventQueueLength = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      subsystem,
+			Name:           "resourceslice_tracker_event_queue_length",
+			Help:           "Number of events waiting to be delivered to the event handlers of the ResourceSlice tracker, categorized by tracker",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"tracker"},
+	)
+
+	// ResourceSliceTrackerHandlerDuration tracks how long the event
+	// handlers of the ResourceSlice tracker take for one event.
+	ResourceSliceTrackerHandlerDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      subsystem,
+			Name:           "resourceslice_tracker_event_handler_duration_seconds",
+			Help:           "Duration of delivering one event to an event handler of the ResourceSlice tracker, categorized by tracker and event type",
+			Buckets:        metrics.ExponentialBuckets(0.0001, 2, 15),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"tracker", "event"},
+	)
+
+	// ResourceSliceTrackerPatchedSlices is the number of ResourceSlices
+	// which get modified by DeviceTaintRules.
+	ResourceSliceTrackerPatchedSlices = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      subsystem,
+			Name:           "resourceslice_tracker_patched_slices",
+			Help:           "Number of ResourceSlices with devices that are tainted by DeviceTaintRules, categorized by tracker",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"tracker"},
+	)
+
+	// ResourceSliceTrackerDeviceTaintRules is the number of
+	// DeviceTaintRules known to the ResourceSlice tracker.
+	ResourceSliceTrackerDeviceTaintRules = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      subsystem,
+			Name:           "resourceslice_tracker_device_taint_rules",
+			Help:           "Number of DeviceTaintRules known to the ResourceSlice tracker, categorized by tracker",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"tracker"},
+	)
+)
+
+var registerMetrics sync.Once
+
+// RegisterMetrics registers ResourceSlice controller and tracker metrics.
+// The controller and tracker update them also when they are not registered.
+func RegisterMetrics() {
+	registerMetrics.Do(func() {
+		legacyregistry.MustRegister(ResourceSliceOperations)
+		legacyregistry.MustRegister(ResourceSliceSyncDuration)
+		legacyregistry.MustRegister(ResourceSliceQueueDepth)
+		legacyregistry.MustRegister(ResourceSliceSyncRetries)
+		legacyregistry.MustRegister(ResourceSliceDroppedFields)
+		legacyregistry.MustRegister(ResourceSlicePublishedDevices)
+		legacyregistry.MustRegister(ResourceSliceTrackerEventQueueLength)
+		legacyregistry.MustRegister(ResourceSliceTrackerHandlerDuration)
+		legacyregistry.MustRegister(ResourceSliceTrackerPatchedSlices)
+		legacyregistry.MustRegister(ResourceSliceTrackerDeviceTaintRules)
+	})
+}
@@ -0,0 +1,85 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceslice
+
+// SliceNamer determines the names of the ResourceSlices of a pool.
+// The controller uses the name of an existing ResourceSlice to
+// match it with the desired slice at the same index in [Pool.Slices],
+// so the index must be recoverable from the name.
+//
+// Because the allocator sorts ResourceSlices by name, a SliceNamer
+// should produce names which sort in the order of their index.
+type SliceNamer interface {
+	// GenerateName returns the prefix for the name of a new
+	// ResourceSlice. The apiserver appends a random suffix. With
+	// server-side apply, the controller appends a hash of the
+	// pool name instead.
+	GenerateName(params SliceNameParams) string
+
+	// SliceIndex returns the index encoded in the name of an
+	// existing ResourceSlice. An error marks the ResourceSlice
+	// as obsolete, for example because it was created with a
+	// different naming scheme or for a different number of slices.
+	// It then gets replaced.
+	SliceIndex(name string, params SliceNameParams) (int, error)
+}
+
+// SliceNameParams contains the information available to a [SliceNamer].
+type SliceNameParams struct {
+	DriverName string
+	PoolName   string
+	// Owner is nil if the controller has no owner.
+	Owner *Owner
+	// Index is the index of the desired slice in [Pool.Slices].
+	// Not set for [SliceNamer.SliceIndex].
+	Index int
+	// NumSlices is the number of slices in the pool.
+	NumSlices int
+}
+
+// DefaultSliceNamer implements the naming scheme that is used when
+// [Options.SliceNamer] is nil:
+//
+//	[index encoded as base16 string]-[driver name]-[owner name (if not nil)]-
+//
+// The index is padded with zeros to the same length in all ResourceSlices
+// of a pool.
+type DefaultSliceNamer struct{}
+
+var _ SliceNamer = DefaultSliceNamer{}
+
+func (DefaultSliceNamer) GenerateName(params SliceNameParams) string {
+	generateName := encodeIndex(params.Index, getIndexLength(params.NumSlices)) + nameSeparator + params.DriverName + nameSeparator
+	if params.Owner != nil {
+		generateName += params.Owner.Name + nameSeparator
+	}
+	return generateName
+}
+
+func (DefaultSliceNamer) SliceIndex(name string, params SliceNameParams) (int, error) {
+	return decodeIndex(name, getIndexLength(params.NumSlices))
+}
+
+func (c *Controller) sliceNameParams(poolName string, index, numSlices int) SliceNameParams {
+	return SliceNameParams{
+		DriverName: c.driverName,
+		PoolName:   poolName,
+		Owner:      c.owner,
+		Index:      index,
+		NumSlices:  numSlices,
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceslice
+
+import (
+	"cmp"
+	"slices"
+
+	resourceapi "k8s.io/api/resource/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/diff"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// OperationType is the kind of API call in a [SliceOperation].
+type OperationType string
+
+const (
+	OperationCreate OperationType = "Create"
+	OperationUpdate OperationType = "Update"
+	OperationDelete OperationType = "Delete"
+)
+
+// DeviceChangeType describes how a device differs between the
+// current and the desired ResourceSlice.
+type DeviceChangeType string
+
+const (
+	DeviceAdded    DeviceChangeType = "Added"
+	DeviceRemoved  DeviceChangeType = "Removed"
+	DeviceModified DeviceChangeType = "Modified"
+)
+
+// PoolPlan lists the operations that the controller would perform
+// for one pool in dry-run mode.
+type PoolPlan struct {
+	// Generation is the pool generation after performing the operations.
+	Generation int64
+	// Operations are sorted in the order in which the controller
+	// would perform them: deletes first, then updates, then creates.
+	// When a new generation gets published atomically (see
+	// [Options.AtomicGenerationUpdates]), updates and creates come
+	// first and run in parallel, then obsolete slices get deleted
+	// unless they were replaced by a slice with the same name.
+	// Empty if the ResourceSlices are up-to-date.
+	Operations []SliceOperation
+}
+
+// SliceOperation is one planned API call.
+type SliceOperation struct {
+	Type OperationType
+	// SliceIndex is the index in [Pool.Slices] of the desired slice.
+	// -1 for deletes.
+	SliceIndex int
+	// Current is the existing ResourceSlice, nil for creates.
+	Current *resourceapi.ResourceSlice
+	// Desired is the ResourceSlice as it would be sent to the
+	// API server, nil for deletes. New slices only have a
+	// GenerateName unless server-side apply is enabled.
+	Desired *resourceapi.ResourceSlice
+	// Devices describes changes of individual devices, in the order
+	// of the desired devices followed by removed devices.
+	Devices []DeviceChange
+	// Diff is a human-readable diff of the ResourceSliceSpec.
+	// Empty for creates and deletes.
+	Diff string
+}
+
+// DeviceChange describes how one device changes.
+type DeviceChange struct {
+	Name string
+	Type DeviceChangeType
+	// Diff is a human-readable diff of the device.
+	// Only set for modified devices.
+	Diff string
+}
+
+// Plan returns the planned operations of all pools which were
+// processed in dry-run mode, by pool name. Pools without
+// ResourceSlices which are not in the desired state anymore are
+// not included. Nil if dry-run mode is not enabled.
+func (c *Controller) Plan() map[string]PoolPlan {
+	if !c.dryRun {
+		return nil
+	}
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	plans := make(map[string]PoolPlan, len(c.plans))
+	for poolName, plan := range c.plans {
+		plans[poolName] = plan
+	}
+	return plans
+}
+
+func (c *Controller) storePlan(poolName string, plan PoolPlan, removed bool) {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	if removed && len(plan.Operations) == 0 {
+		delete(c.plans, poolName)
+		return
+	}
+	c.plans[poolName] = plan
+}
+
+// planPool determines which operations syncPool would perform,
+// using the same input as syncPool.
+func (c *Controller) planPool(poolName string, pool Pool, obsoleteSlices []*resourceapi.ResourceSlice, currentSliceForDesiredSlice map[int]*resourceapi.ResourceSlice, changedDesiredSlices sets.Set[int], bumpedGeneration bool, nodeName string, desiredPool resourceapi.ResourcePool) PoolPlan {
+	plan := PoolPlan{
+		Generation: desiredPool.Generation,
+	}
+	var deletes []SliceOperation
+	for _, slice := range obsoleteSlices {
+		deletes = append(deletes, SliceOperation{
+			Type:       OperationDelete,
+			SliceIndex: -1,
+			Current:    slice,
+			Devices:    diffDevices(slice.Spec.Devices, nil),
+		})
+	}
+	// Same order as in syncPool.
+	atomicUpdate := c.atomicGenerations && bumpedGeneration
+	if !atomicUpdate {
+		plan.Operations = append(plan.Operations, deletes...)
+	}
+	var updates []SliceOperation
+	for i, currentSlice := range currentSliceForDesiredSlice {
+		if !changedDesiredSlices.Has(i) && !bumpedGeneration {
+			continue
+		}
+		slice := updatedSlice(currentSlice, pool, i, desiredPool)
+		updates = append(updates, SliceOperation{
+			Type:       OperationUpdate,
+			SliceIndex: i,
+			Current:    currentSlice,
+			Desired:    slice,
+			Devices:    diffDevices(currentSlice.Spec.Devices, slice.Spec.Devices),
+			Diff:       diff.Diff(currentSlice.Spec, slice.Spec),
+		})
+	}
+	slices.SortFunc(updates, func(a, b SliceOperation) int { return cmp.Compare(a.SliceIndex, b.SliceIndex) })
+	plan.Operations = append(plan.Operations, updates...)
+	for i := range pool.Slices {
+		if _, ok := currentSliceForDesiredSlice[i]; ok {
+			continue
+		}
+		slice := c.newSlice(poolName, pool, i, nodeName, desiredPool)
+		plan.Operations = append(plan.Operations, SliceOperation{
+			Type:       OperationCreate,
+			SliceIndex: i,
+			Desired:    slice,
+			Devices:    diffDevices(nil, slice.Spec.Devices),
+		})
+	}
+	if atomicUpdate {
+		// With server-side apply, an obsolete slice may get
+		// replaced by a new one with the same name.
+		writtenNames := sets.New[string]()
+		for _, operation := range plan.Operations {
+			writtenNames.Insert(operation.Desired.Name)
+		}
+		for _, operation := range deletes {
+			if !writtenNames.Has(operation.Current.Name) {
+				plan.Operations = append(plan.Operations, operation)
+			}
+		}
+	}
+	return plan
+}
+
+// diffDevices compares devices by name.
+func diffDevices(current, desired []resourceapi.Device) []DeviceChange {
+	var changes []DeviceChange
+	currentByName := make(map[string]*resourceapi.Device, len(current))
+	for i := range current {
+		currentByName[current[i].Name] = &current[i]
+	}
+	desiredNames := sets.New[string]()
+	for i := range desired {
+		device := &desired[i]
+		desiredNames.Insert(device.Name)
+		currentDevice, ok := currentByName[device.Name]
+		switch {
+		case !ok:
+			changes = append(changes, DeviceChange{Name: device.Name, Type: DeviceAdded})
+		case !apiequality.Semantic.DeepEqual(currentDevice, device):
+			changes = append(changes, DeviceChange{Name: device.Name, Type: DeviceModified, Diff: diff.Diff(currentDevice, device)})
+		}
+	}
+	for i := range current {
+		if !desiredNames.Has(current[i].Name) {
+			changes = append(changes, DeviceChange{Name: current[i].Name, Type: DeviceRemoved})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,90 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceslice
+
+import (
+	"sync/atomic"
+
+	"k8s.io/client-go/util/workqueue"
+	resourceslicemetrics "k8s.io/dynamic-resource-allocation/resourceslice/metrics"
+)
+
+// queueMetricsProvider connects the depth of the work queue to
+// the ResourceSliceQueueDepth metric of the driver. The queue
+// updates it whenever a pool gets added or removed, including
+// delayed additions once they become ready. The other queue
+// metrics are not reported.
+type queueMetricsProvider struct {
+	driverName string
+}
+
+var _ workqueue.MetricsProvider = queueMetricsProvider{}
+
+func (p queueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return &queueDepthMetric{driverName: p.driverName}
+}
+
+func (p queueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return noopMetric{}
+}
+
+func (p queueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return noopMetric{}
+}
+
+func (p queueMetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return noopMetric{}
+}
+
+func (p queueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return noopMetric{}
+}
+
+func (p queueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return noopMetric{}
+}
+
+func (p queueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return noopMetric{}
+}
+
+// queueDepthMetric counts itself and sets the gauge to the current value,
+// which also works when the metrics get registered only after creating
+// the controller.
+type queueDepthMetric struct {
+	driverName string
+	depth      atomic.Int64
+}
+
+func (m *queueDepthMetric) Inc() {
+	m.set(m.depth.Add(1))
+}
+
+func (m *queueDepthMetric) Dec() {
+	m.set(m.depth.Add(-1))
+}
+
+func (m *queueDepthMetric) set(depth int64) {
+	resourceslicemetrics.ResourceSliceQueueDepth.WithLabelValues(m.driverName).Set(float64(depth))
+}
+
+type noopMetric struct{}
+
+func (noopMetric) Inc()            {}
+func (noopMetric) Dec()            {}
+func (noopMetric) Set(float64)     {}
+func (noopMetric) Observe(float64) {}
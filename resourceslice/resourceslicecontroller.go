@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"slices"
 	"strconv"
 	"strings"
@@ -34,6 +35,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/conversion"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/diff"
@@ -43,8 +45,10 @@ import (
 	"k8s.io/client-go/kubernetes"
 	cgocore "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/flowcontrol"
 	"k8s.io/client-go/util/workqueue"
 	draclient "k8s.io/dynamic-resource-allocation/client"
+	resourceslicemetrics "k8s.io/dynamic-resource-allocation/resourceslice/metrics"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/ptr"
 )
@@ -68,6 +72,10 @@ const (
 	// doesn't get fixed while that human is waiting for it.
 	DefaultSyncDelay = 30 * time.Second
 
+	// DefaultFieldManagerPrefix is combined with the driver name to form
+	// the default field manager for server-side apply.
+	DefaultFieldManagerPrefix = "resourceslice-controller-"
+
 	// resourceSliceIndexMinLength is the minimum length of the encoded index in the
 	// ResourceSlice name.
 	//
@@ -126,6 +134,66 @@ type Controller struct {
 
 	// Optional pool name to reconcile.
 	reconcilePoolWithName string
+
+	// allDeviceTaints and deviceTaints (pool name -> device name -> taints)
+	// get added to the devices in the desired state. Like resources,
+	// they get replaced instead of modified.
+	allDeviceTaints []resourceapi.DeviceTaint
+	deviceTaints    map[string]map[string][]resourceapi.DeviceTaint
+
+	// dryRun disables all writes, see Options.DryRun.
+	dryRun bool
+	// plans contains the result of the most recent syncPool in dry-run mode.
+	// Protected by statusMutex.
+	plans map[string]PoolPlan
+
+	sliceNamer SliceNamer
+	labels     map[string]string
+	cleanup    CleanupStrategy
+
+	// fallback is the optional Options.Fallback. degradedFeatures
+	// contains the features that it was applied for. Like resources,
+	// the set gets replaced instead of modified.
+	fallback         *FallbackPolicy
+	degradedFeatures sets.Set[string]
+
+	// generations contains the most recent generation published
+	// for each pool, see Options.GenerationStore. Only accessed
+	// by the worker.
+	generations       map[string]int64
+	generationStore   GenerationStore
+	atomicGenerations bool
+
+	// publishedDevices contains the number of devices published for
+	// each pool, numPublishedDevices the sum. Only accessed by the worker.
+	publishedDevices    map[string]int
+	numPublishedDevices int
+
+	// writeLimiter is the optional client-side rate limit for writes.
+	writeLimiter flowcontrol.RateLimiter
+	// updateDelay is the optional Options.UpdateDelay.
+	updateDelay time.Duration
+
+	// fence is checked before each write if set, see
+	// StartLeaderElectedController.
+	fence func() error
+
+	// fieldManager is non-empty if server-side apply is enabled.
+	fieldManager string
+
+	// desiredVersions gets updated together with resources. For each
+	// pool which was updated or removed, it contains the value of
+	// lastVersion at the time of that change. Protected by mutex.
+	desiredVersions map[string]int64
+	lastVersion     int64
+
+	// statusMutex protects poolStatuses and statusChanged.
+	statusMutex sync.Mutex
+	// poolStatuses contains the outcome of syncPool, by pool name.
+	poolStatuses map[string]*poolStatus
+	// statusChanged gets closed and replaced each time that
+	// poolStatuses changes.
+	statusChanged chan struct{}
 }
 
 // +k8s:deepcopy-gen=true
@@ -189,6 +257,14 @@ type Pool struct {
 	// because it shows that the driver is up-and-running
 	// and simply doesn't have any devices.
 	Slices []Slice
+
+	// Devices and SharedCounters are an alternative to Slices.
+	// If either of them is set, then Slices must be empty. The
+	// controller then splits the devices and counter sets into
+	// slices with [ChunkDevices], keeping devices in the slices
+	// where they were published by the previous Update.
+	Devices        []resourceapi.Device
+	SharedCounters []resourceapi.CounterSet
 }
 
 // +k8s:deepcopy-gen=true
@@ -214,12 +290,18 @@ type Owner struct {
 
 // StartController constructs a new controller and starts it.
 func StartController(ctx context.Context, options Options) (*Controller, error) {
-	logger := klog.FromContext(ctx)
 	c, err := newController(ctx, options)
 	if err != nil {
 		return nil, fmt.Errorf("create controller: %w", err)
 	}
 
+	c.start(ctx)
+	return c, nil
+}
+
+// start runs the controller in the background until it gets stopped.
+func (c *Controller) start(ctx context.Context) {
+	logger := klog.FromContext(ctx)
 	logger.V(3).Info("Starting")
 	c.wg.Add(1)
 	go func() {
@@ -227,7 +309,23 @@ func StartController(ctx context.Context, options Options) (*Controller, error)
 		defer logger.V(3).Info("Stopping")
 		c.run(ctx)
 	}()
-	return c, nil
+}
+
+// beforeWrite returns an error if the controller must not write.
+// Otherwise it waits until the client-side rate limit permits
+// another API call.
+func (c *Controller) beforeWrite(ctx context.Context) error {
+	if c.fence != nil {
+		if err := c.fence(); err != nil {
+			return err
+		}
+	}
+	if c.writeLimiter != nil {
+		if err := c.writeLimiter.Wait(ctx); err != nil {
+			return fmt.Errorf("client-side rate limit: %w", err)
+		}
+	}
+	return nil
 }
 
 // Options contains various optional settings for [StartController].
@@ -256,6 +354,7 @@ type Options struct {
 	Resources *DriverResources
 
 	// Queue can be used to override the default work queue implementation.
+	// The queue depth metric is only reported by the default queue.
 	Queue workqueue.TypedRateLimitingInterface[string]
 
 	// MutationCacheTTL can be used to change the default TTL of one minute.
@@ -303,6 +402,95 @@ type Options struct {
 	//
 	// Empty means the default behavior.
 	ReconcilePoolWithName string
+
+	// ServerSideApply enables publishing ResourceSlices with server-side
+	// apply instead of create and update calls. ResourceSlices then
+	// get deterministic names instead of names with a random suffix.
+	// Applying the same slice twice is harmless, so the controller
+	// reads directly from its informer cache without a mutation cache
+	// and MutationCacheTTL is ignored.
+	//
+	// Fields owned by a different field manager cause a conflict
+	// error, which gets reported through the ErrorHandler and
+	// retried. The controller never forces ownership.
+	//
+	// Requires the resource.k8s.io/v1 or v1beta2 API.
+	ServerSideApply bool
+
+	// DryRun disables creating, updating and deleting ResourceSlices.
+	// Instead, the controller computes which operations it would perform
+	// and makes them available through [Controller.Plan].
+	//
+	// In this mode, a pool counts as published as soon as its
+	// operations are planned, so [Controller.WaitForPublished]
+	// can be used to wait for a complete plan.
+	DryRun bool
+
+	// SliceNamer determines the names of new ResourceSlices and how
+	// existing ResourceSlices get matched with the desired slices.
+	// The default is [DefaultSliceNamer].
+	SliceNamer SliceNamer
+
+	// Labels get added to all ResourceSlices published by the controller.
+	// If set, the controller only watches ResourceSlices which have these
+	// labels. This is an alternative to Owner for identifying the
+	// ResourceSlices managed by the controller, for example when several
+	// controllers for the same driver publish different pools without
+	// having a cluster-scoped owner.
+	//
+	// During startup, ResourceSlices with these labels which belong to a
+	// pool that is not in the desired state get deleted immediately
+	// instead of after the SyncDelay. This cleans up after a previous
+	// instance of the controller.
+	Labels map[string]string
+
+	// Cleanup decides whether ResourceSlices of pools that are not part
+	// of the desired state get deleted. The default is to delete them.
+	// See [OrphanDetector] for an alternative.
+	Cleanup CleanupStrategy
+
+	// GenerationStore persists the generation of each pool across
+	// controller restarts. The default is to only remember it
+	// while the controller runs.
+	GenerationStore GenerationStore
+
+	// AtomicGenerationUpdates publishes a new generation of a pool
+	// as atomically as the API permits: all ResourceSlices get written
+	// in parallel and obsolete ResourceSlices only get deleted afterwards.
+	// The allocator ignores a pool while its newest generation is
+	// incomplete, so this keeps that time short for pools with many
+	// ResourceSlices. The downside is a burst of API calls.
+	//
+	// Without this, obsolete ResourceSlices get deleted first and
+	// the other ResourceSlices get written one after the other.
+	AtomicGenerationUpdates bool
+
+	// QPS and Burst limit the rate of create, update and delete
+	// calls for ResourceSlices, in addition to the rate limit
+	// of the KubeClient. This avoids bursts of API calls when
+	// many pools change at once. Zero QPS means no additional
+	// limit. Burst defaults to one.
+	QPS   float32
+	Burst int
+
+	// UpdateDelay delays the sync of a pool after its desired state
+	// was changed, for example by [Controller.Update]. Further changes
+	// during that time get coalesced into the same sync. Pools which
+	// lose devices always get synced immediately and before other pools
+	// because removing devices is more urgent than publishing new ones.
+	//
+	// The default is to sync immediately.
+	UpdateDelay *time.Duration
+
+	// Fallback enables publishing ResourceSlices without the fields
+	// which depend on features that are disabled in the cluster.
+	// See [FallbackPolicy].
+	Fallback *FallbackPolicy
+
+	// FieldManager is used with ServerSideApply. The default is
+	// [DefaultFieldManagerPrefix] + driver name. All instances
+	// of a driver must use the same field manager.
+	FieldManager string
 }
 
 // DroppedFieldsError is reported through the ErrorHandler in [Options] if
@@ -390,12 +578,9 @@ func (c *Controller) Update(resources *DriverResources) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	// Sync all old pools..
-	if c.resources != nil {
-		for poolName := range c.resources.Pools {
-			c.queue.Add(poolName)
-		}
-	}
+	// Sync all old pools and the new ones (might be the same),
+	// also if the new resources get rejected.
+	defer c.resourcesChanged(c.resources)
 
 	if resources == nil {
 		c.resources = &DriverResources{}
@@ -414,14 +599,104 @@ func (c *Controller) Update(resources *DriverResources) {
 			}
 		}
 
-		c.resources = resources.DeepCopy()
+		newResources := resources.DeepCopy()
+		if err := chunkPools(c.resources, newResources); err != nil {
+			c.errorHandler(context.Background(), err, "processing update DriverResources")
+			return
+		}
+		c.resources = newResources
 		roundTaintTimeAdded(c.resources)
 	}
+}
 
-	// ... and the new ones (might be the same).
-	for poolName := range c.resources.Pools {
-		c.queue.Add(poolName)
+// resourcesChanged must be called with c.mutex locked after replacing
+// c.resources. Removing devices is more urgent than publishing new ones,
+// so pools which lose devices get synced first and without the
+// UpdateDelay.
+func (c *Controller) resourcesChanged(oldResources *DriverResources) {
+	var newPools map[string]Pool
+	if c.resources != nil {
+		newPools = c.resources.Pools
+	}
+	var oldPools map[string]Pool
+	if oldResources != nil {
+		oldPools = oldResources.Pools
+	}
+	var otherPools []string
+	for poolName, oldPool := range oldPools {
+		newPool, ok := newPools[poolName]
+		if !ok || removesDevices(oldPool, newPool) {
+			c.poolChangedAfter(poolName, 0)
+			continue
+		}
+		otherPools = append(otherPools, poolName)
+	}
+	for poolName := range newPools {
+		if _, ok := oldPools[poolName]; !ok {
+			otherPools = append(otherPools, poolName)
+		}
+	}
+	for _, poolName := range otherPools {
+		c.poolChanged(poolName)
+	}
+}
+
+// removesDevices returns true if some device of the old pool
+// is not in the new pool.
+func removesDevices(oldPool, newPool Pool) bool {
+	newDevices := sets.New[string]()
+	for _, slice := range newPool.Slices {
+		for _, device := range slice.Devices {
+			newDevices.Insert(device.Name)
+		}
+	}
+	for _, slice := range oldPool.Slices {
+		for _, device := range slice.Devices {
+			if !newDevices.Has(device.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// chunkPools fills in the slices of pools which use automatic chunking,
+// based on the slices in the previous resources (may be nil).
+func chunkPools(oldResources, newResources *DriverResources) error {
+	for poolName, pool := range newResources.Pools {
+		var oldPool *Pool
+		if oldResources != nil {
+			if p, ok := oldResources.Pools[poolName]; ok {
+				oldPool = &p
+			}
+		}
+		if err := chunkPool(poolName, oldPool, &pool); err != nil {
+			return err
+		}
+		newResources.Pools[poolName] = pool
+	}
+	return nil
+}
+
+// chunkPool fills in the slices of the pool if it uses automatic chunking.
+// oldPool is the previous state of the pool, if there was one.
+func chunkPool(poolName string, oldPool, pool *Pool) error {
+	if len(pool.Devices) == 0 && len(pool.SharedCounters) == 0 {
+		return nil
+	}
+	if len(pool.Slices) > 0 {
+		return fmt.Errorf("pool %q: Slices must be empty when using Devices or SharedCounters", poolName)
+	}
+	var previous []Slice
+	if oldPool != nil && (len(oldPool.Devices) > 0 || len(oldPool.SharedCounters) > 0) {
+		previous = oldPool.Slices
 	}
+	slices, err := ChunkDevices(previous, pool.Devices, pool.SharedCounters, ChunkOptions{})
+	if err != nil {
+		return fmt.Errorf("pool %q: %w", poolName, err)
+	}
+	pool.Slices = slices
+	return nil
 }
 
 // roundTaintTimeAdded rounds all timestamps to seconds because that is all
@@ -429,18 +704,102 @@ func (c *Controller) Update(resources *DriverResources) {
 // desired and actual stored slice.
 func roundTaintTimeAdded(resources *DriverResources) {
 	for _, pool := range resources.Pools {
-		for _, slice := range pool.Slices {
-			for _, device := range slice.Devices {
-				for _, taint := range device.Taints {
-					if taint.TimeAdded != nil {
-						taint.TimeAdded.Time = taint.TimeAdded.Time.Round(time.Second)
-					}
-				}
-			}
+		roundPoolTaintTimeAdded(pool)
+	}
+}
+
+func roundPoolTaintTimeAdded(pool Pool) {
+	for _, slice := range pool.Slices {
+		for _, device := range slice.Devices {
+			roundDeviceTaintTimeAdded(device.Taints)
 		}
 	}
 }
 
+func roundDeviceTaintTimeAdded(taints []resourceapi.DeviceTaint) {
+	for _, taint := range taints {
+		if taint.TimeAdded != nil {
+			taint.TimeAdded.Time = taint.TimeAdded.Time.Round(time.Second)
+		}
+	}
+}
+
+// UpdatePool sets the new desired state of one pool. In contrast to
+// [Controller.Update], only this pool gets synchronized again.
+//
+// It returns the version of the desired state which includes this
+// update. [Controller.WaitForPoolVersion] can be used to wait until
+// the ResourceSlices reflect it.
+//
+// The controller is doing a deep copy, so the caller may update
+// the instance once UpdatePool returns.
+func (c *Controller) UpdatePool(poolName string, pool Pool) (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.reconcilePoolWithName != "" && poolName != c.reconcilePoolWithName {
+		return 0, fmt.Errorf("ReconcilePoolWithName=%q, but got an update for pool %q", c.reconcilePoolWithName, poolName)
+	}
+
+	newPool := pool.DeepCopy()
+	var oldPool *Pool
+	if p, ok := c.resources.Pools[poolName]; ok {
+		oldPool = &p
+	}
+
+	if err := chunkPool(poolName, oldPool, newPool); err != nil {
+		return 0, err
+	}
+	roundPoolTaintTimeAdded(*newPool)
+
+	// c.resources gets read without holding the mutex while syncing,
+	// so it must be replaced instead of modified in place. The other
+	// pools are immutable and can be shared.
+	resources := &DriverResources{Pools: make(map[string]Pool, len(c.resources.Pools)+1)}
+	for name, p := range c.resources.Pools {
+		resources.Pools[name] = p
+	}
+	resources.Pools[poolName] = *newPool
+	c.resources = resources
+	if oldPool != nil && removesDevices(*oldPool, *newPool) {
+		c.poolChangedAfter(poolName, 0)
+	} else {
+		c.poolChanged(poolName)
+	}
+	return c.lastVersion, nil
+}
+
+// RemovePool removes one pool. Its ResourceSlices get deleted.
+// Other pools are not affected.
+func (c *Controller) RemovePool(poolName string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.resources.Pools[poolName]; !ok {
+		return
+	}
+	resources := &DriverResources{Pools: make(map[string]Pool, len(c.resources.Pools))}
+	for name, p := range c.resources.Pools {
+		if name != poolName {
+			resources.Pools[name] = p
+		}
+	}
+	c.resources = resources
+	c.poolChangedAfter(poolName, 0)
+}
+
+// recordOperation updates the metrics for a ResourceSlice API call.
+func (c *Controller) recordOperation(operation string, err error) {
+	resourceslicemetrics.ResourceSliceOperations.WithLabelValues(c.driverName, operation, statusLabel(err)).Inc()
+}
+
+func statusLabel(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
 // GetStats provides some insights into operations of the controller.
 func (c *Controller) GetStats() Stats {
 	s := Stats{
@@ -483,11 +842,45 @@ func newController(ctx context.Context, options Options) (*Controller, error) {
 		errorHandler:          options.ErrorHandler,
 		lastAddByPool:         make(map[string]time.Time),
 		reconcilePoolWithName: options.ReconcilePoolWithName,
+		poolStatuses:          make(map[string]*poolStatus),
+		dryRun:                options.DryRun,
+		plans:                 make(map[string]PoolPlan),
+		statusChanged:         make(chan struct{}),
+		sliceNamer:            options.SliceNamer,
+		labels:                maps.Clone(options.Labels),
+		cleanup:               options.Cleanup,
+		generations:           make(map[string]int64),
+		publishedDevices:      make(map[string]int),
+		generationStore:       options.GenerationStore,
+		atomicGenerations:     options.AtomicGenerationUpdates,
+		updateDelay:           ptr.Deref(options.UpdateDelay, 0),
+	}
+	if options.QPS > 0 {
+		c.writeLimiter = flowcontrol.NewTokenBucketRateLimiter(options.QPS, max(options.Burst, 1))
+	}
+	if options.Fallback != nil {
+		fallback := *options.Fallback
+		c.fallback = &fallback
+	}
+	if c.sliceNamer == nil {
+		c.sliceNamer = DefaultSliceNamer{}
+	}
+	if c.cleanup == nil {
+		c.cleanup = deleteUnknownPools{}
+	}
+	if options.ServerSideApply {
+		c.fieldManager = options.FieldManager
+		if c.fieldManager == "" {
+			c.fieldManager = DefaultFieldManagerPrefix + c.driverName
+		}
 	}
 	if c.queue == nil {
 		c.queue = workqueue.NewTypedRateLimitingQueueWithConfig(
 			workqueue.DefaultTypedControllerRateLimiter[string](),
-			workqueue.TypedRateLimitingQueueConfig[string]{Name: "node_resource_slices"},
+			workqueue.TypedRateLimitingQueueConfig[string]{
+				Name:            "node_resource_slices",
+				MetricsProvider: queueMetricsProvider{driverName: c.driverName},
+			},
 		)
 	}
 	if c.errorHandler == nil {
@@ -495,17 +888,22 @@ func newController(ctx context.Context, options Options) (*Controller, error) {
 			utilruntime.HandleErrorWithContext(ctx, err, msg)
 		}
 	}
-	if err := c.initInformer(ctx); err != nil {
+	indexer, err := c.initInformer(ctx)
+	if err != nil {
 		return nil, err
 	}
 
 	c.Update(options.Resources)
+	if len(c.labels) > 0 {
+		c.sweep(indexer)
+	}
 
 	return c, nil
 }
 
 // initInformer initializes the informer used to watch for changes to the resources slice.
-func (c *Controller) initInformer(ctx context.Context) error {
+// It returns the indexer of the informer once it has synced.
+func (c *Controller) initInformer(ctx context.Context) (cache.Indexer, error) {
 	logger := klog.FromContext(ctx)
 
 	// We always filter by driver name, by node name only for node-local resources.
@@ -521,6 +919,9 @@ func (c *Controller) initInformer(ctx context.Context) error {
 	}
 	tweakListOptions := func(options *metav1.ListOptions) {
 		options.FieldSelector = selector.String()
+		if len(c.labels) > 0 {
+			options.LabelSelector = labels.SelectorFromSet(c.labels).String()
+		}
 	}
 	indexers := cache.Indexers{
 		poolNameIndex: func(obj interface{}) ([]string, error) {
@@ -567,7 +968,17 @@ func (c *Controller) initInformer(ctx context.Context) error {
 		0,
 		indexers,
 	)
-	c.sliceStore = cache.NewIntegerResourceVersionMutationCache(logger, informer.GetStore(), informer.GetIndexer(), c.mutationCacheTTL, true /* includeAdds */)
+	if c.fieldManager != "" {
+		// The mutation cache prevents creating the same slice twice
+		// when the informer cache is not up-to-date yet. With
+		// server-side apply, the names are deterministic, so
+		// there are no duplicates. Syncing with outdated slices
+		// merely leads to redundant applies or to deletes which
+		// fail because of the preconditions and get retried.
+		c.sliceStore = indexerWithoutMutations{Indexer: informer.GetIndexer()}
+	} else {
+		c.sliceStore = cache.NewIntegerResourceVersionMutationCache(logger, informer.GetStore(), informer.GetIndexer(), c.mutationCacheTTL, true /* includeAdds */)
+	}
 	handler, err := informer.AddEventHandlerWithOptions(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj any) {
 			slice, ok := obj.(*resourceapi.ResourceSlice)
@@ -613,7 +1024,7 @@ func (c *Controller) initInformer(ctx context.Context) error {
 		},
 	}, cache.HandlerOptions{Logger: &logger})
 	if err != nil {
-		return fmt.Errorf("registering event handler on the ResourceSlice informer: %w", err)
+		return nil, fmt.Errorf("registering event handler on the ResourceSlice informer: %w", err)
 	}
 	// Start informer and wait for our cache to be populated.
 	logger.V(3).Info("Starting ResourceSlice informer and waiting for it to sync")
@@ -628,11 +1039,11 @@ func (c *Controller) initInformer(ctx context.Context) error {
 		select {
 		case <-time.After(time.Second):
 		case <-ctx.Done():
-			return fmt.Errorf("sync ResourceSlice informer: %w", context.Cause(ctx))
+			return nil, fmt.Errorf("sync ResourceSlice informer: %w", context.Cause(ctx))
 		}
 	}
 	logger.V(3).Info("ResourceSlice informer has synced")
-	return nil
+	return informer.GetIndexer(), nil
 }
 
 // run is running in the background.
@@ -649,10 +1060,18 @@ func (c *Controller) processNextWorkItem(ctx context.Context) bool {
 	defer c.queue.Done(poolName)
 	logger := klog.FromContext(ctx)
 
+	start := time.Now()
 	err := c.syncPool(klog.NewContext(ctx, klog.LoggerWithValues(logger, "poolName", poolName)), poolName)
+	duration := time.Since(start)
+	resourceslicemetrics.ResourceSliceSyncDuration.WithLabelValues(c.driverName, statusLabel(err)).Observe(duration.Seconds())
+	// The metric doesn't have the pool name because the number of pools
+	// is unbounded, so the per-pool duration only gets logged.
+	logger.V(5).Info("Synchronized pool", "poolName", poolName, "duration", duration, "err", err)
 	if err != nil {
+		c.poolFailed(poolName, err)
 		c.errorHandler(ctx, err, "processing ResourceSlice objects")
 		c.queue.AddRateLimited(poolName)
+		resourceslicemetrics.ResourceSliceSyncRetries.WithLabelValues(c.driverName).Inc()
 
 		// Return without removing the work item from the queue.
 		// It will be retried.
@@ -683,8 +1102,13 @@ func (c *Controller) syncPool(ctx context.Context, poolName string) error {
 	var resources *DriverResources
 	c.mutex.RLock()
 	resources = c.resources
+	version, previouslyDesired := c.desiredVersions[poolName]
+	allDeviceTaints := c.allDeviceTaints
+	deviceTaints := c.deviceTaints[poolName]
+	degradedFeatures := c.degradedFeatures
 	c.mutex.RUnlock()
-	if err := validateDriverResources(resources); err != nil {
+	if err := validateDriverResources(c.driverName, resources); err != nil {
+		c.poolInvalid(poolName, version, err)
 		c.errorHandler(ctx, err, "pool validation failed")
 		// We only report the error through the error handler to prevent
 		// the controller from retrying.
@@ -692,6 +1116,28 @@ func (c *Controller) syncPool(ctx context.Context, poolName string) error {
 	}
 
 	pool, ok := resources.Pools[poolName]
+	if !ok && len(slices) > 0 && !previouslyDesired {
+		// Not a pool that we removed ourselves, so it might be managed by someone else.
+		shouldDelete, recheck, err := c.cleanup.ShouldDelete(ctx, c.driverName, poolName, slices)
+		if err != nil {
+			return fmt.Errorf("check unknown pool: %w", err)
+		}
+		if !shouldDelete {
+			logger.V(5).Info("Keeping resource slices of unknown pool", "recheckAfter", recheck)
+			// Forget a status left behind by a failed check.
+			c.poolRemoved(poolName, version)
+			if recheck > 0 {
+				c.queue.AddAfter(poolName, recheck)
+			}
+			return nil
+		}
+	}
+	if !ok && c.dryRun {
+		// All slices are obsolete.
+		c.storePlan(poolName, c.planPool(poolName, Pool{}, slices, nil, nil, false, "", resourceapi.ResourcePool{}), true)
+		c.poolRemoved(poolName, version)
+		return nil
+	}
 	if !ok {
 		if len(slices) > 0 {
 			// All are obsolete, pool does not exist anymore.
@@ -700,9 +1146,12 @@ func (c *Controller) syncPool(ctx context.Context, poolName string) error {
 				return fmt.Errorf("remove slices: %w", err)
 			}
 		}
+		c.setPublishedDevices(poolName, 0)
+		c.poolRemoved(poolName, version)
 		// Pool does not exist anymore, nothing more to do.
 		return nil
 	}
+	pool = addTaints(pool, allDeviceTaints, deviceTaints)
 
 	// Retrieve node object to get UID?
 	// The result gets cached and is expected to not change while
@@ -722,6 +1171,15 @@ func (c *Controller) syncPool(ctx context.Context, poolName string) error {
 		}
 	}
 
+	pool, err = degradePool(pool, degradedFeatures, nodeName)
+	if err != nil {
+		err = fmt.Errorf("pool %q cannot be published with the fallback policy: %w", poolName, err)
+		c.poolInvalid(poolName, version, err)
+		c.errorHandler(ctx, err, "applying fallback policy failed")
+		// Same as for validation errors: retrying would fail again.
+		return nil
+	}
+
 	// Determine highest generation.
 	var generation int64
 	for _, slice := range slices {
@@ -729,12 +1187,20 @@ func (c *Controller) syncPool(ctx context.Context, poolName string) error {
 			generation = slice.Spec.Pool.Generation
 		}
 	}
+	// A new generation must also be higher than any generation
+	// published before, even if those slices are gone or not
+	// in the informer cache (yet).
+	storedGeneration, err := c.storedGeneration(ctx, poolName)
+	if err != nil {
+		return err
+	}
+	nextGeneration := max(generation, storedGeneration) + 1
 
 	// Classify existing slices. If an existing slice has wrong generation
 	// or doesn't match a desired index, it's obsolete.
 	currentSliceForDesiredSlice := make(map[int]*resourceapi.ResourceSlice, len(pool.Slices))
 	obsoleteSlices := make([]*resourceapi.ResourceSlice, 0, len(slices))
-	expectedPrefixLength := getIndexLength(len(pool.Slices))
+	nameParams := c.sliceNameParams(poolName, 0, len(pool.Slices))
 
 	for _, slice := range slices {
 		// Wrong generation is always obsolete.
@@ -743,7 +1209,7 @@ func (c *Controller) syncPool(ctx context.Context, poolName string) error {
 			continue
 		}
 
-		index, err := decodeIndex(slice.Name, expectedPrefixLength)
+		index, err := c.sliceNamer.SliceIndex(slice.Name, nameParams)
 		if err != nil {
 			logger.V(5).Info("Unmatched existing slice (invalid name)", "slice", klog.KObj(slice), "err", err)
 			obsoleteSlices = append(obsoleteSlices, slice)
@@ -794,7 +1260,7 @@ func (c *Controller) syncPool(ctx context.Context, poolName string) error {
 
 	bumpedGeneration := false
 	switch {
-	case pool.Generation > generation:
+	case pool.Generation >= nextGeneration:
 		// Bump up the generation if the driver asked for it, or
 		// start with a non-zero generation.
 		generation = pool.Generation
@@ -803,106 +1269,100 @@ func (c *Controller) syncPool(ctx context.Context, poolName string) error {
 	case numNewSlices == 0 && len(changedDesiredSlices) <= 1:
 		logger.V(5).Info("Kept generation because at most one update API call is necessary", "generation", generation)
 	default:
-		generation++
+		generation = nextGeneration
 		bumpedGeneration = true
-		logger.V(5).Info("Bumped generation by one", "generation", generation)
+		logger.V(5).Info("Bumped generation", "generation", generation)
 	}
 	desiredPool.Generation = generation
 
+	if c.dryRun {
+		c.storePlan(poolName, c.planPool(poolName, pool, obsoleteSlices, currentSliceForDesiredSlice, changedDesiredSlices, bumpedGeneration, nodeName, desiredPool), false)
+		c.poolPublished(poolName, version, generation)
+		return nil
+	}
+
 	// First delete obsolete slices. If the desired slices are faulty, then it's still better to
 	// remove devices that the driver no longer has, even if we cannot publish the new ones.
-	if err := c.removeSlices(ctx, obsoleteSlices); err != nil {
-		return fmt.Errorf("remove slices: %w", err)
+	//
+	// When publishing a new generation atomically, the obsolete slices
+	// get deleted last. The old generation then remains complete until
+	// the first slice of the new generation gets stored.
+	atomicUpdate := c.atomicGenerations && bumpedGeneration
+	if !atomicUpdate {
+		if err := c.removeSlices(ctx, obsoleteSlices); err != nil {
+			return fmt.Errorf("remove slices: %w", err)
+		}
 	}
 
-	// Update existing slices.
+	// Update existing slices, then create new slices.
+	var writes []sliceWrite
 	for i, currentSlice := range currentSliceForDesiredSlice {
 		if !changedDesiredSlices.Has(i) && !bumpedGeneration {
 			continue
 		}
-		slice := currentSlice.DeepCopy()
-		slice.Spec.Pool = desiredPool
-		// No need to set the node name. If it was different, we wouldn't
-		// have listed the existing slice.
-		//
-		// When adding new fields here, then also extend sliceStored.
-		slice.Spec.NodeSelector = pool.NodeSelector
-		slice.Spec.AllNodes = refIfNotZero(pool.AllNodes)
-		slice.Spec.SharedCounters = pool.Slices[i].SharedCounters
-		slice.Spec.PerDeviceNodeSelection = pool.Slices[i].PerDeviceNodeSelection
-		// Preserve TimeAdded from existing device, if there is a matching device and taint.
-		slice.Spec.Devices = copyTaintTimeAdded(slice.Spec.Devices, pool.Slices[i].Devices)
-
-		actualSlice, err := c.resourceClient.ResourceSlices().Update(ctx, slice, metav1.UpdateOptions{})
-		if err != nil {
-			return fmt.Errorf("update resource slice: %w", err)
-		}
-		logger.V(5).Info("Updated existing resource slice", "slice", klog.KObj(slice))
-		atomic.AddInt64(&c.numUpdates, 1)
-		c.sliceStored(ctx, "update ResourceSlice", poolName, pool, i, slice, actualSlice)
+		writes = append(writes, sliceWrite{index: i, slice: updatedSlice(currentSlice, pool, i, desiredPool)})
 	}
-
-	// Create new slices.
-	added := false
 	for i := 0; i < len(pool.Slices); i++ {
 		if _, ok := currentSliceForDesiredSlice[i]; ok {
 			// Was handled above through an update.
 			continue
 		}
-		var ownerReferences []metav1.OwnerReference
-		if c.owner != nil {
-			ownerReferences = append(ownerReferences,
-				metav1.OwnerReference{
-					APIVersion: c.owner.APIVersion,
-					Kind:       c.owner.Kind,
-					Name:       c.owner.Name,
-					UID:        c.owner.UID,
-					Controller: ptr.To(true),
-				},
-			)
+		writes = append(writes, sliceWrite{index: i, slice: c.newSlice(poolName, pool, i, nodeName, desiredPool), create: true})
+	}
+	added := false
+	if atomicUpdate {
+		// All slices of the new generation get written in parallel
+		// to keep the time where the pool is incomplete short.
+		actualSlices := make([]*resourceapi.ResourceSlice, len(writes))
+		errs := make([]error, len(writes))
+		var wg sync.WaitGroup
+		for i := range writes {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				actualSlices[i], errs[i] = c.writeSlice(ctx, writes[i])
+			}()
 		}
-		// The GenerateName follows the scheme:
-		// [index encoded as base16 string]-[driver name]-[owner name (if not nil)]-
-		// This ensures that the index is at the beginning of the name,
-		// and the API server handles uniqueness by appending a random suffix.
-		generateName := encodeIndex(i, expectedPrefixLength) + nameSeparator + c.driverName + nameSeparator
-		if c.owner != nil {
-			generateName += c.owner.Name + nameSeparator
+		wg.Wait()
+		writtenNames := sets.New[string]()
+		for i, write := range writes {
+			if errs[i] != nil {
+				continue
+			}
+			writtenNames.Insert(actualSlices[i].Name)
+			if write.create && c.fieldManager == "" {
+				added = true
+			}
+			c.sliceStored(ctx, write.operation()+" ResourceSlice", poolName, pool, write.index, write.slice, actualSlices[i])
 		}
-		slice := &resourceapi.ResourceSlice{
-			ObjectMeta: metav1.ObjectMeta{
-				OwnerReferences: ownerReferences,
-				GenerateName:    generateName,
-			},
-			Spec: resourceapi.ResourceSliceSpec{
-				Driver:                 c.driverName,
-				Pool:                   desiredPool,
-				NodeName:               refIfNotZero(nodeName),
-				NodeSelector:           pool.NodeSelector,
-				AllNodes:               refIfNotZero(pool.AllNodes),
-				Devices:                pool.Slices[i].Devices,
-				SharedCounters:         pool.Slices[i].SharedCounters,
-				PerDeviceNodeSelection: pool.Slices[i].PerDeviceNodeSelection,
-			},
+		if err := errors.Join(errs...); err != nil {
+			return err
 		}
-
-		// It can happen that we create a missing slice, some
-		// other change than the create causes another sync of
-		// the pool, and then a second slice for the same set
-		// of devices would get created because the controller has
-		// no copy of the first slice instance in its informer
-		// cache yet.
-		//
-		// Using a https://pkg.go.dev/k8s.io/client-go/tools/cache#MutationCache
-		// avoids that.
-		actualSlice, err := c.resourceClient.ResourceSlices().Create(ctx, slice, metav1.CreateOptions{})
-		if err != nil {
-			return fmt.Errorf("create resource slice: %w", err)
+		// With server-side apply, an obsolete slice may have been
+		// replaced by a new one with the same name.
+		var remainingSlices []*resourceapi.ResourceSlice
+		for _, slice := range obsoleteSlices {
+			if !writtenNames.Has(slice.Name) {
+				remainingSlices = append(remainingSlices, slice)
+			}
 		}
-		logger.V(5).Info("Created new resource slice", "slice", klog.KObj(actualSlice))
-		atomic.AddInt64(&c.numCreates, 1)
-		added = true
-		c.sliceStored(ctx, "create ResourceSlice", poolName, pool, i, slice, actualSlice)
+		if err := c.removeSlices(ctx, remainingSlices); err != nil {
+			return fmt.Errorf("remove slices: %w", err)
+		}
+	} else {
+		for _, write := range writes {
+			actualSlice, err := c.writeSlice(ctx, write)
+			if err != nil {
+				return err
+			}
+			if write.create && c.fieldManager == "" {
+				added = true
+			}
+			c.sliceStored(ctx, write.operation()+" ResourceSlice", poolName, pool, write.index, write.slice, actualSlice)
+		}
+	}
+	if err := c.generationPublished(ctx, poolName, generation); err != nil {
+		return err
 	}
 
 	now := time.Now()
@@ -929,10 +1389,147 @@ func (c *Controller) syncPool(ctx context.Context, poolName string) error {
 		c.queue.AddAfter(poolName, when.Sub(now))
 		logger.V(5).Info("Scheduled re-sync", "at", when)
 	}
+	numDevices := 0
+	for _, slice := range pool.Slices {
+		numDevices += len(slice.Devices)
+	}
+	c.setPublishedDevices(poolName, numDevices)
+	c.poolPublished(poolName, version, generation)
 
 	return nil
 }
 
+// setPublishedDevices updates the number of devices published for the pool
+// and the metric with the total for the driver. Zero removes the pool.
+func (c *Controller) setPublishedDevices(poolName string, numDevices int) {
+	c.numPublishedDevices += numDevices - c.publishedDevices[poolName]
+	if numDevices == 0 {
+		delete(c.publishedDevices, poolName)
+	} else {
+		c.publishedDevices[poolName] = numDevices
+	}
+	resourceslicemetrics.ResourceSlicePublishedDevices.WithLabelValues(c.driverName).Set(float64(c.numPublishedDevices))
+}
+
+// sliceWrite is one create or update of a ResourceSlice in syncPool.
+type sliceWrite struct {
+	// index is the index of the desired slice.
+	index  int
+	slice  *resourceapi.ResourceSlice
+	create bool
+}
+
+func (w sliceWrite) operation() string {
+	if w.create {
+		return "create"
+	}
+	return "update"
+}
+
+// writeSlice creates or updates one ResourceSlice. It may get called
+// in parallel for different slices.
+func (c *Controller) writeSlice(ctx context.Context, write sliceWrite) (*resourceapi.ResourceSlice, error) {
+	logger := klog.FromContext(ctx)
+	operation := write.operation()
+	if err := c.beforeWrite(ctx); err != nil {
+		return nil, fmt.Errorf("%s resource slice: %w", operation, err)
+	}
+	var actualSlice *resourceapi.ResourceSlice
+	var err error
+	switch {
+	case c.fieldManager != "":
+		// With server-side apply, the name is deterministic and
+		// applying the same slice twice is harmless.
+		actualSlice, err = c.applySlice(ctx, write.slice)
+	case write.create:
+		// It can happen that we create a missing slice, some
+		// other change than the create causes another sync of
+		// the pool, and then a second slice for the same set
+		// of devices would get created because the controller has
+		// no copy of the first slice instance in its informer
+		// cache yet.
+		//
+		// Using a https://pkg.go.dev/k8s.io/client-go/tools/cache#MutationCache
+		// avoids that.
+		actualSlice, err = c.resourceClient.ResourceSlices().Create(ctx, write.slice, metav1.CreateOptions{})
+	default:
+		actualSlice, err = c.resourceClient.ResourceSlices().Update(ctx, write.slice, metav1.UpdateOptions{})
+	}
+	c.recordOperation(operation, err)
+	if err != nil {
+		return nil, fmt.Errorf("%s resource slice: %w", operation, err)
+	}
+	if write.create {
+		logger.V(5).Info("Created new resource slice", "slice", klog.KObj(actualSlice))
+		atomic.AddInt64(&c.numCreates, 1)
+	} else {
+		logger.V(5).Info("Updated existing resource slice", "slice", klog.KObj(write.slice))
+		atomic.AddInt64(&c.numUpdates, 1)
+	}
+	return actualSlice, nil
+}
+
+// updatedSlice returns a copy of the current slice with the desired content.
+func updatedSlice(currentSlice *resourceapi.ResourceSlice, pool Pool, i int, desiredPool resourceapi.ResourcePool) *resourceapi.ResourceSlice {
+	slice := currentSlice.DeepCopy()
+	slice.Spec.Pool = desiredPool
+	// No need to set the node name. If it was different, we wouldn't
+	// have listed the existing slice.
+	//
+	// When adding new fields here, then also extend sliceStored.
+	slice.Spec.NodeSelector = pool.NodeSelector
+	slice.Spec.AllNodes = refIfNotZero(pool.AllNodes)
+	slice.Spec.SharedCounters = pool.Slices[i].SharedCounters
+	slice.Spec.PerDeviceNodeSelection = pool.Slices[i].PerDeviceNodeSelection
+	// Preserve TimeAdded from existing device, if there is a matching device and taint.
+	slice.Spec.Devices = copyTaintTimeAdded(slice.Spec.Devices, pool.Slices[i].Devices)
+	return slice
+}
+
+// newSlice returns a new slice for the desired slice with index i.
+func (c *Controller) newSlice(poolName string, pool Pool, i int, nodeName string, desiredPool resourceapi.ResourcePool) *resourceapi.ResourceSlice {
+	var ownerReferences []metav1.OwnerReference
+	if c.owner != nil {
+		ownerReferences = append(ownerReferences,
+			metav1.OwnerReference{
+				APIVersion: c.owner.APIVersion,
+				Kind:       c.owner.Kind,
+				Name:       c.owner.Name,
+				UID:        c.owner.UID,
+				Controller: ptr.To(true),
+			},
+		)
+	}
+	// By default, the GenerateName follows the scheme:
+	// [index encoded as base16 string]-[driver name]-[owner name (if not nil)]-
+	// This ensures that the index is at the beginning of the name,
+	// and the API server handles uniqueness by appending a random suffix.
+	generateName := c.sliceNamer.GenerateName(c.sliceNameParams(poolName, i, len(pool.Slices)))
+	slice := &resourceapi.ResourceSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			OwnerReferences: ownerReferences,
+			GenerateName:    generateName,
+			Labels:          c.labels,
+		},
+		Spec: resourceapi.ResourceSliceSpec{
+			Driver:                 c.driverName,
+			Pool:                   desiredPool,
+			NodeName:               refIfNotZero(nodeName),
+			NodeSelector:           pool.NodeSelector,
+			AllNodes:               refIfNotZero(pool.AllNodes),
+			Devices:                pool.Slices[i].Devices,
+			SharedCounters:         pool.Slices[i].SharedCounters,
+			PerDeviceNodeSelection: pool.Slices[i].PerDeviceNodeSelection,
+		},
+	}
+	if c.fieldManager != "" {
+		// With server-side apply, the name is deterministic.
+		slice.Name = applySliceName(generateName, poolName)
+		slice.GenerateName = ""
+	}
+	return slice
+}
+
 func (c *Controller) removeSlices(ctx context.Context, slices []*resourceapi.ResourceSlice) error {
 	logger := klog.FromContext(ctx)
 
@@ -951,14 +1548,19 @@ func (c *Controller) removeSlices(ctx context.Context, slices []*resourceapi.Res
 		// If this happens, we get a "not found error" and nothing
 		// changes on the server. The only downside is the extra API
 		// call. This isn't as bad as extra creates.
+		if err := c.beforeWrite(ctx); err != nil {
+			return fmt.Errorf("delete resource slice: %w", err)
+		}
 		err := c.resourceClient.ResourceSlices().Delete(ctx, slice.Name, options)
 		switch {
 		case err == nil:
 			logger.V(5).Info("Deleted obsolete resource slice", "slice", klog.KObj(slice), "deleteOptions", options)
 			atomic.AddInt64(&c.numDeletes, 1)
+			c.recordOperation("delete", nil)
 		case apierrors.IsNotFound(err):
 			logger.V(5).Info("Resource slice was already deleted earlier", "slice", klog.KObj(slice))
 		default:
+			c.recordOperation("delete", err)
 			return fmt.Errorf("delete resource slice: %w", err)
 		}
 	}
@@ -987,16 +1589,30 @@ func (c *Controller) sliceStored(ctx context.Context, msg string, poolName strin
 	if !apiequality.Semantic.DeepEqual(desiredSlice.Spec.PerDeviceNodeSelection, actualSlice.Spec.PerDeviceNodeSelection) ||
 		!apiequality.Semantic.DeepEqual(desiredSlice.Spec.SharedCounters, actualSlice.Spec.SharedCounters) ||
 		!apiequality.Semantic.DeepEqual(desiredSlice.Spec.Devices, actualSlice.Spec.Devices) {
-		pool.Slices[sliceIndex].PerDeviceNodeSelection = actualSlice.Spec.PerDeviceNodeSelection
-		pool.Slices[sliceIndex].SharedCounters = actualSlice.Spec.SharedCounters
-		pool.Slices[sliceIndex].Devices = actualSlice.Spec.Devices
-
 		err := &DroppedFieldsError{
 			PoolName:     poolName,
 			SliceIndex:   sliceIndex,
 			DesiredSlice: desiredSlice.DeepCopy(),
 			ActualSlice:  actualSlice.DeepCopy(),
 		}
+		disabled := err.DisabledFeatures()
+		for _, feature := range disabled {
+			resourceslicemetrics.ResourceSliceDroppedFields.WithLabelValues(c.driverName, feature).Inc()
+		}
+		if c.fallback != nil {
+			if features := c.degrade(disabled); len(features) > 0 {
+				// The desired state remains unchanged. The next sync
+				// publishes the degraded variant of it.
+				klog.FromContext(ctx).Info("Applying fallback policy for disabled features", "features", features)
+				c.errorHandler(ctx, &FallbackError{DroppedFieldsError: err, Features: features}, msg)
+				return
+			}
+		}
+
+		pool.Slices[sliceIndex].PerDeviceNodeSelection = actualSlice.Spec.PerDeviceNodeSelection
+		pool.Slices[sliceIndex].SharedCounters = actualSlice.Spec.SharedCounters
+		pool.Slices[sliceIndex].Devices = actualSlice.Spec.Devices
+
 		c.errorHandler(ctx, err, msg)
 	}
 }
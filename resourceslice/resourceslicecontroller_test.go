@@ -18,6 +18,7 @@ package resourceslice
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
@@ -34,11 +35,13 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/kubernetes/fake"
 	k8stesting "k8s.io/client-go/testing"
 	"k8s.io/dynamic-resource-allocation/internal/workqueue"
@@ -1184,7 +1187,7 @@ func TestControllerSyncPool(t *testing.T) {
 			expectedStats: Stats{
 				NumCreates: 0,
 			},
-			expectedErrors: []string{`pool validation failed: found duplicate counter set "counterset" in pool "pool"`},
+			expectedErrors: []string{`pool validation failed: pools[pool].slices[1].sharedCounters[0].name: Duplicate value: "counterset"`},
 		},
 		"detect-duplicate-devices": {
 			nodeUID: nodeUID,
@@ -1214,7 +1217,7 @@ func TestControllerSyncPool(t *testing.T) {
 			expectedStats: Stats{
 				NumCreates: 0,
 			},
-			expectedErrors: []string{`pool validation failed: found duplicate device "device" in pool "pool"`},
+			expectedErrors: []string{`pool validation failed: pools[pool].slices[1].devices[0].name: Duplicate value: "device"`},
 		},
 		"detect-device-referencing-unknown-counter-set": {
 			nodeUID: nodeUID,
@@ -1242,7 +1245,7 @@ func TestControllerSyncPool(t *testing.T) {
 			expectedStats: Stats{
 				NumCreates: 0,
 			},
-			expectedErrors: []string{`pool validation failed: counter set "counterset" referenced by device "device" not found`},
+			expectedErrors: []string{`pool validation failed: pools[pool].slices[0].devices[0].consumesCounters[0].counterSet: Not found: "counterset"`},
 		},
 		"detect-device-referencing-unknown-counter-in-counter-set": {
 			nodeUID: nodeUID,
@@ -1287,7 +1290,7 @@ func TestControllerSyncPool(t *testing.T) {
 			expectedStats: Stats{
 				NumCreates: 0,
 			},
-			expectedErrors: []string{`pool validation failed: counter "cpu" referenced by device "device" not found in counter set "counterset"`},
+			expectedErrors: []string{`pool validation failed: pools[pool].slices[1].devices[0].consumesCounters[0].counters[cpu]: Invalid value: "cpu": counter not found in counter set "counterset"`},
 		},
 		"migration-from-random-naming-to-index-based-naming": {
 			nodeUID: nodeUID,
@@ -1567,6 +1570,886 @@ func TestControllerUpdateReconcilePoolWithNameValidation(t *testing.T) {
 	}
 }
 
+// TestControllerUpdatePool verifies that UpdatePool and RemovePool only
+// sync the affected pool and that the publication status is tracked.
+func TestControllerUpdatePool(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	const (
+		driverName = "driver.example.com"
+		poolA      = "pool-a"
+		poolB      = "pool-b"
+	)
+	kubeClient := createTestClient(features{}, metav1.Now())
+	var queue workqueue.Mock[string]
+	var controllerErrors []error
+	ctrl, err := newController(ctx, Options{
+		DriverName: driverName,
+		KubeClient: kubeClient,
+		Resources: &DriverResources{
+			Pools: map[string]Pool{
+				poolA: {AllNodes: true, Slices: []Slice{{Devices: []resourceapi.Device{{Name: "dev-a"}}}}},
+				poolB: {AllNodes: true, Slices: []Slice{{Devices: []resourceapi.Device{{Name: "dev-b"}}}}},
+			},
+		},
+		Queue: &queue,
+		ErrorHandler: func(ctx context.Context, err error, msg string) {
+			controllerErrors = append(controllerErrors, fmt.Errorf("%s: %w", msg, err))
+		},
+	})
+	require.NoError(t, err, "unexpected controller creation error")
+	defer ctrl.Stop()
+	ctrl.run(ctx)
+
+	numSlices := func(poolName string) int {
+		t.Helper()
+		slices, err := kubeClient.ResourceV1().ResourceSlices().List(ctx, metav1.ListOptions{})
+		require.NoError(t, err, "list resource slices")
+		num := 0
+		for _, slice := range slices.Items {
+			if slice.Spec.Pool.Name == poolName {
+				num++
+			}
+		}
+		return num
+	}
+	assert.Equal(t, 1, numSlices(poolA))
+	assert.Equal(t, 1, numSlices(poolB))
+	generation, ok := ctrl.PublishedGeneration(poolA)
+	assert.True(t, ok, "pool A published")
+	assert.Equal(t, int64(1), generation)
+	require.NoError(t, ctrl.WaitForPublished(ctx))
+
+	version, err := ctrl.UpdatePool(poolA, Pool{AllNodes: true, Generation: 5, Slices: []Slice{{Devices: []resourceapi.Device{{Name: "dev-a"}, {Name: "dev-c"}}}}})
+	require.NoError(t, err, "update pool A")
+	assert.Equal(t, []string{poolA}, queue.State().Ready, "only pool A queued")
+
+	// Not published yet.
+	status, ok := ctrl.PoolStatus(poolA)
+	require.True(t, ok, "pool A status")
+	assert.False(t, status.Published, "pool A published")
+	assert.Equal(t, int64(5), status.DesiredGeneration)
+	assert.Equal(t, int64(1), status.PublishedGeneration)
+	assert.True(t, ctrl.PoolStatuses()[poolB].Published, "pool B published")
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	require.ErrorIs(t, ctrl.WaitForPoolVersion(canceledCtx, poolA, version), context.Canceled)
+	require.ErrorIs(t, ctrl.WaitForPublished(canceledCtx), context.Canceled)
+
+	ctrl.run(ctx)
+	require.NoError(t, ctrl.WaitForPoolVersion(ctx, poolA, version))
+	require.NoError(t, ctrl.WaitForPublished(ctx))
+	generation, _ = ctrl.PublishedGeneration(poolA)
+	assert.Equal(t, int64(5), generation)
+	status, _ = ctrl.PoolStatus(poolA)
+	assert.True(t, status.Published, "pool A published")
+	assert.NoError(t, status.LastError)
+	assert.False(t, status.LastSyncTime.IsZero(), "pool A sync time")
+
+	// A lower generation from the driver gets ignored by the controller,
+	// which doesn't matter for the version.
+	version, err = ctrl.UpdatePool(poolA, Pool{AllNodes: true, Generation: 1, Slices: []Slice{{Devices: []resourceapi.Device{{Name: "dev-a"}}}}})
+	require.NoError(t, err, "update pool A")
+	require.ErrorIs(t, ctrl.WaitForPoolVersion(canceledCtx, poolA, version), context.Canceled)
+	ctrl.run(ctx)
+	require.NoError(t, ctrl.WaitForPoolVersion(ctx, poolA, version))
+
+	ctrl.RemovePool(poolB)
+	assert.Equal(t, []string{poolB}, queue.State().Ready, "only pool B queued")
+	require.ErrorIs(t, ctrl.WaitForPublished(canceledCtx), context.Canceled, "pool B not removed yet")
+	ctrl.run(ctx)
+	require.NoError(t, ctrl.WaitForPublished(ctx))
+	assert.Equal(t, 1, numSlices(poolA))
+	assert.Equal(t, 0, numSlices(poolB))
+	_, ok = ctrl.PublishedGeneration(poolB)
+	assert.False(t, ok, "pool B removed")
+	ctrl.mutex.RLock()
+	assert.NotContains(t, ctrl.desiredVersions, poolB, "desired version of pool B")
+	ctrl.mutex.RUnlock()
+	ctrl.statusMutex.Lock()
+	assert.NotContains(t, ctrl.poolStatuses, poolB, "status of pool B")
+	ctrl.statusMutex.Unlock()
+	assert.Empty(t, controllerErrors)
+}
+
+// TestControllerServerSideApply verifies that slices get published with
+// server-side apply and that conflicts with other field managers are reported.
+func TestControllerDeviceTaints(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	const (
+		driverName = "driver.example.com"
+		poolA      = "pool-a"
+	)
+	ownTaint := resourceapi.DeviceTaint{Key: "example.com/own", Effect: resourceapi.DeviceTaintEffectNoSchedule}
+	shutdownTaint := resourceapi.DeviceTaint{Key: "example.com/shutdown", Effect: resourceapi.DeviceTaintEffectNoSchedule}
+	unhealthyTaint := resourceapi.DeviceTaint{Key: "example.com/unhealthy", Effect: resourceapi.DeviceTaintEffectNoExecute}
+	kubeClient := createTestClient(features{}, metav1.Now())
+	var queue workqueue.Mock[string]
+	var controllerErrors []error
+	ctrl, err := newController(ctx, Options{
+		DriverName: driverName,
+		KubeClient: kubeClient,
+		Resources: &DriverResources{
+			Pools: map[string]Pool{
+				poolA: {AllNodes: true, Slices: []Slice{{Devices: []resourceapi.Device{
+					{Name: "dev-a", Taints: []resourceapi.DeviceTaint{ownTaint}},
+					{Name: "dev-b"},
+				}}}},
+			},
+		},
+		Queue: &queue,
+		ErrorHandler: func(ctx context.Context, err error, msg string) {
+			controllerErrors = append(controllerErrors, fmt.Errorf("%s: %w", msg, err))
+		},
+	})
+	require.NoError(t, err, "unexpected controller creation error")
+	defer ctrl.Stop()
+	ctrl.run(ctx)
+
+	taintKeys := func() map[string][]string {
+		t.Helper()
+		slices, err := kubeClient.ResourceV1().ResourceSlices().List(ctx, metav1.ListOptions{})
+		require.NoError(t, err, "list resource slices")
+		keys := make(map[string][]string)
+		for _, slice := range slices.Items {
+			for _, device := range slice.Spec.Devices {
+				keys[device.Name] = nil
+				for _, taint := range device.Taints {
+					keys[device.Name] = append(keys[device.Name], taint.Key)
+				}
+			}
+		}
+		return keys
+	}
+	assert.Equal(t, map[string][]string{"dev-a": {ownTaint.Key}, "dev-b": nil}, taintKeys())
+
+	ctrl.SetAllDeviceTaints(shutdownTaint, ownTaint)
+	assert.Equal(t, []string{poolA}, queue.State().Ready, "pool A queued")
+	ctrl.run(ctx)
+	require.NoError(t, ctrl.WaitForPublished(ctx))
+	assert.Equal(t, map[string][]string{"dev-a": {ownTaint.Key, shutdownTaint.Key}, "dev-b": {shutdownTaint.Key, ownTaint.Key}}, taintKeys())
+
+	ctrl.SetDeviceTaints(poolA, "dev-b", unhealthyTaint)
+	ctrl.SetDeviceTaints("pool-b", "dev-c", unhealthyTaint)
+	assert.Equal(t, []string{poolA}, queue.State().Ready, "only pool A queued")
+	assert.Equal(t, []resourceapi.DeviceTaint{unhealthyTaint}, ctrl.DeviceTaints(poolA, "dev-b"))
+	ctrl.SetAllDeviceTaints()
+	ctrl.run(ctx)
+	assert.Equal(t, map[string][]string{"dev-a": {ownTaint.Key}, "dev-b": {unhealthyTaint.Key}}, taintKeys())
+
+	// Recovery.
+	ctrl.SetDeviceTaints(poolA, "dev-b")
+	ctrl.run(ctx)
+	assert.Equal(t, map[string][]string{"dev-a": {ownTaint.Key}, "dev-b": nil}, taintKeys())
+	assert.Empty(t, ctrl.DeviceTaints(poolA, "dev-b"))
+	assert.Empty(t, controllerErrors)
+}
+
+type poolSliceNamer struct{}
+
+func (poolSliceNamer) GenerateName(params SliceNameParams) string {
+	return fmt.Sprintf("%s-%d-", params.PoolName, params.Index)
+}
+
+func (poolSliceNamer) SliceIndex(name string, params SliceNameParams) (int, error) {
+	var index int
+	if _, err := fmt.Sscanf(strings.TrimPrefix(name, params.PoolName+"-"), "%d-", &index); err != nil {
+		return -1, err
+	}
+	return index, nil
+}
+
+func TestControllerCleanup(t *testing.T) {
+	const (
+		driverName  = "driver.example.com"
+		poolName    = "pool"
+		orphanPool  = "orphan-pool"
+		foreignPool = "foreign-pool"
+	)
+	managedLabels := map[string]string{"example.com/managed-by": "controller-a"}
+	existingSlice := func(name, poolName string, labels map[string]string) *resourceapi.ResourceSlice {
+		slice := MakeResourceSlice().Name(name).Driver(driverName).AllNodes(true).
+			Pool(resourceapi.ResourcePool{Name: poolName, Generation: 1, ResourceSliceCount: 1}).Obj()
+		slice.Labels = labels
+		return slice
+	}
+	resources := &DriverResources{
+		Pools: map[string]Pool{
+			poolName: {AllNodes: true, Slices: []Slice{{Devices: []resourceapi.Device{{Name: "dev-a"}}}}},
+		},
+	}
+
+	testcases := map[string]struct {
+		initialObjects []runtime.Object
+		options        Options
+		expectedSlices map[string]map[string]string
+		expectedLater  []workqueue.MockDelayedItem[string]
+	}{
+		"label-sweep": {
+			initialObjects: []runtime.Object{
+				existingSlice("orphan", orphanPool, managedLabels),
+				existingSlice("foreign", foreignPool, nil),
+			},
+			options: Options{Labels: managedLabels},
+			expectedSlices: map[string]map[string]string{
+				"foreign":                    nil,
+				"00000-" + driverName + "-0": managedLabels,
+			},
+		},
+		"orphan-detector": {
+			initialObjects: []runtime.Object{
+				existingSlice("orphan", orphanPool, nil),
+				existingSlice("foreign", foreignPool, nil),
+			},
+			options: Options{
+				SyncDelay: ptr.To(time.Duration(0)),
+				Cleanup: OrphanDetector{
+					IsOrphaned: func(ctx context.Context, driverName, poolName string) (bool, error) {
+						return poolName == orphanPool, nil
+					},
+				},
+			},
+			expectedSlices: map[string]map[string]string{
+				"foreign":                    nil,
+				"00000-" + driverName + "-0": nil,
+			},
+			expectedLater: []workqueue.MockDelayedItem[string]{{Item: foreignPool, Duration: DefaultOrphanRecheckInterval}},
+		},
+		"slice-namer": {
+			options: Options{SliceNamer: poolSliceNamer{}},
+			expectedSlices: map[string]map[string]string{
+				poolName + "-0-0": nil,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			_, ctx := ktesting.NewTestContext(t)
+			kubeClient := createTestClient(features{}, metav1.Now(), tc.initialObjects...)
+			var queue workqueue.Mock[string]
+			var controllerErrors []error
+			options := tc.options
+			options.DriverName = driverName
+			options.KubeClient = kubeClient
+			options.Resources = resources
+			options.Queue = &queue
+			options.ErrorHandler = func(ctx context.Context, err error, msg string) {
+				controllerErrors = append(controllerErrors, fmt.Errorf("%s: %w", msg, err))
+			}
+			ctrl, err := newController(ctx, options)
+			require.NoError(t, err, "unexpected controller creation error")
+			defer ctrl.Stop()
+			ctrl.run(ctx)
+
+			slices, err := kubeClient.ResourceV1().ResourceSlices().List(ctx, metav1.ListOptions{})
+			require.NoError(t, err, "list resource slices")
+			actualSlices := make(map[string]map[string]string)
+			for _, slice := range slices.Items {
+				actualSlices[slice.Name] = slice.Labels
+			}
+			assert.Equal(t, tc.expectedSlices, actualSlices)
+			// Informer events for the slices created and deleted above
+			// may add further delayed work items, so only check that
+			// the expected ones are present.
+			later := queue.State().Later
+			for _, item := range tc.expectedLater {
+				assert.Contains(t, later, item)
+			}
+			assert.Empty(t, controllerErrors)
+		})
+	}
+}
+
+func TestControllerFence(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	const driverName = "driver.example.com"
+	kubeClient := createTestClient(features{}, metav1.Now())
+	var queue workqueue.Mock[string]
+	var controllerErrors []error
+	ctrl, err := newController(ctx, Options{
+		DriverName: driverName,
+		KubeClient: kubeClient,
+		Resources: &DriverResources{
+			Pools: map[string]Pool{
+				"pool": {AllNodes: true, Slices: []Slice{{Devices: []resourceapi.Device{{Name: "dev-a"}}}}},
+			},
+		},
+		Queue: &queue,
+		ErrorHandler: func(ctx context.Context, err error, msg string) {
+			controllerErrors = append(controllerErrors, err)
+		},
+	})
+	require.NoError(t, err, "unexpected controller creation error")
+	defer ctrl.Stop()
+	errNotLeader := errors.New("not the leader")
+	ctrl.fence = func() error { return errNotLeader }
+
+	// Process only the work item added by Update. A failed sync gets
+	// retried through the rate limiter, which the mock queue doesn't
+	// process.
+	ctrl.run(ctx)
+
+	slices, err := kubeClient.ResourceV1().ResourceSlices().List(ctx, metav1.ListOptions{})
+	require.NoError(t, err, "list resource slices")
+	assert.Empty(t, slices.Items)
+	require.Len(t, controllerErrors, 1)
+	assert.ErrorIs(t, controllerErrors[0], errNotLeader)
+}
+
+func TestControllerFallback(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	const driverName = "driver.example.com"
+	consumes := func(value string) []resourceapi.DeviceCounterConsumption {
+		return []resourceapi.DeviceCounterConsumption{{
+			CounterSet: "gpu-0",
+			Counters:   map[string]resourceapi.Counter{"memory": {Value: resource.MustParse(value)}},
+		}}
+	}
+	taint := resourceapi.DeviceTaint{Key: "example.com/taint", Effect: resourceapi.DeviceTaintEffectNoSchedule}
+	kubeClient := createTestClient(features{disablePartitionableDevices: true, disableDeviceTaints: true}, metav1.Now())
+	var queue workqueue.Mock[string]
+	var controllerErrors []error
+	ctrl, err := newController(ctx, Options{
+		DriverName: driverName,
+		KubeClient: kubeClient,
+		Resources: &DriverResources{
+			Pools: map[string]Pool{
+				"pool": {AllNodes: true, Slices: []Slice{{
+					SharedCounters: []resourceapi.CounterSet{{
+						Name:     "gpu-0",
+						Counters: map[string]resourceapi.Counter{"memory": {Value: resource.MustParse("8Gi")}},
+					}},
+					Devices: []resourceapi.Device{
+						{Name: "whole", ConsumesCounters: consumes("8Gi"), Taints: []resourceapi.DeviceTaint{taint}},
+						{Name: "half-a", ConsumesCounters: consumes("4Gi")},
+						{Name: "half-b", ConsumesCounters: consumes("4Gi")},
+						{Name: "plain"},
+					},
+				}}},
+			},
+		},
+		Queue:    &queue,
+		Fallback: &FallbackPolicy{FlattenPartitionableDevices: true},
+		ErrorHandler: func(ctx context.Context, err error, msg string) {
+			controllerErrors = append(controllerErrors, err)
+		},
+	})
+	require.NoError(t, err, "unexpected controller creation error")
+	defer ctrl.Stop()
+	ctrl.run(ctx)
+
+	slices, err := kubeClient.ResourceV1().ResourceSlices().List(ctx, metav1.ListOptions{})
+	require.NoError(t, err, "list resource slices")
+	require.Len(t, slices.Items, 1)
+	// Taints are not covered by the policy, so they only get dropped by the apiserver.
+	assert.Equal(t, []resourceapi.Device{{Name: "whole"}, {Name: "plain"}}, slices.Items[0].Spec.Devices)
+	assert.Empty(t, slices.Items[0].Spec.SharedCounters)
+	assert.Equal(t, []string{"DRAPartitionableDevices"}, ctrl.DegradedFeatures())
+
+	require.NotEmpty(t, controllerErrors)
+	var fallbackErr *FallbackError
+	require.ErrorAs(t, controllerErrors[0], &fallbackErr)
+	assert.Equal(t, []string{"DRAPartitionableDevices"}, fallbackErr.Features)
+	var droppedFields *DroppedFieldsError
+	assert.ErrorAs(t, controllerErrors[0], &droppedFields, "FallbackError wraps DroppedFieldsError")
+	for _, err := range controllerErrors[1:] {
+		assert.NotErrorAs(t, err, &fallbackErr, "fallback must only be reported once")
+	}
+}
+
+func TestControllerFallbackWithoutNodeSelection(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	const driverName = "driver.example.com"
+	kubeClient := createTestClient(features{disablePartitionableDevices: true}, metav1.Now())
+	var queue workqueue.Mock[string]
+	var controllerErrors []error
+	ctrl, err := newController(ctx, Options{
+		DriverName: driverName,
+		KubeClient: kubeClient,
+		Resources: &DriverResources{
+			Pools: map[string]Pool{
+				// A network-attached pool: only the devices
+				// define where they are available.
+				"pool": {Slices: []Slice{{
+					PerDeviceNodeSelection: ptr.To(true),
+					Devices: []resourceapi.Device{
+						{Name: "dev-a", AllNodes: ptr.To(true)},
+					},
+				}}},
+			},
+		},
+		Queue:    &queue,
+		Fallback: &FallbackPolicy{FlattenPartitionableDevices: true},
+		ErrorHandler: func(ctx context.Context, err error, msg string) {
+			controllerErrors = append(controllerErrors, err)
+		},
+	})
+	require.NoError(t, err, "unexpected controller creation error")
+	defer ctrl.Stop()
+	ctrl.run(ctx)
+
+	require.GreaterOrEqual(t, len(controllerErrors), 2)
+	var fallbackErr *FallbackError
+	require.ErrorAs(t, controllerErrors[0], &fallbackErr)
+	assert.ErrorContains(t, controllerErrors[len(controllerErrors)-1], `pool "pool" cannot be published with the fallback policy`)
+	assert.Empty(t, queue.State().Ready, "pool must not get retried")
+
+	// Waiting would never end, so the error gets returned immediately.
+	assert.ErrorContains(t, ctrl.WaitForPublished(ctx), `pool "pool" cannot be published with the fallback policy`)
+	assert.ErrorContains(t, ctrl.WaitForPoolVersion(ctx, "pool", 1), `pool "pool" cannot be published with the fallback policy`)
+	status, ok := ctrl.PoolStatus("pool")
+	require.True(t, ok, "pool status")
+	assert.False(t, status.Published, "pool published")
+}
+
+type memoryGenerationStore map[string]int64
+
+func (s memoryGenerationStore) Generation(ctx context.Context, poolName string) (int64, error) {
+	return s[poolName], nil
+}
+
+func (s memoryGenerationStore) SetGeneration(ctx context.Context, poolName string, generation int64) error {
+	s[poolName] = generation
+	return nil
+}
+
+func TestConfigMapGenerationStore(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	kubeClient := fake.NewClientset()
+	store := NewConfigMapGenerationStore(kubeClient, "default", "driver.example.com", &Owner{APIVersion: "v1", Kind: "Node", Name: "worker-1"})
+	assert.Equal(t, "driver.example.com-node-worker-1-generations", store.Name)
+
+	// Pool names which are not valid keys and only differ in invalid characters.
+	poolNames := []string{"pool/a", "pool_a", "pool:a", strings.Repeat("x", 300)}
+	for i, poolName := range poolNames {
+		require.NoError(t, store.SetGeneration(ctx, poolName, int64(i+1)), "set generation of pool %q", poolName)
+	}
+	for i, poolName := range poolNames {
+		generation, err := store.Generation(ctx, poolName)
+		require.NoError(t, err, "get generation of pool %q", poolName)
+		assert.Equal(t, int64(i+1), generation, "generation of pool %q", poolName)
+	}
+	configMap, err := kubeClient.CoreV1().ConfigMaps("default").Get(ctx, store.Name, metav1.GetOptions{})
+	require.NoError(t, err, "get ConfigMap")
+	assert.Len(t, configMap.Data, len(poolNames))
+	for key := range configMap.Data {
+		assert.Empty(t, validation.IsConfigMapKey(key), "key %q", key)
+	}
+
+	longName := generationConfigMapName(strings.Repeat("d", 200)+".example.com", &Owner{Kind: "Node", Name: strings.Repeat("n", 100)})
+	assert.Empty(t, validation.IsDNS1123Subdomain(longName), "name %q", longName)
+}
+
+func TestControllerGeneration(t *testing.T) {
+	const (
+		driverName = "driver.example.com"
+		poolName   = "pool"
+	)
+	legacySlice := MakeResourceSlice().Name("legacy").Driver(driverName).AllNodes(true).
+		Pool(resourceapi.ResourcePool{Name: poolName, Generation: 1, ResourceSliceCount: 1}).Obj()
+	resources := &DriverResources{
+		Pools: map[string]Pool{
+			poolName: {AllNodes: true, Slices: []Slice{
+				{Devices: []resourceapi.Device{{Name: "dev-a"}}},
+				{Devices: []resourceapi.Device{{Name: "dev-b"}}},
+			}},
+		},
+	}
+
+	testcases := map[string]struct {
+		initialObjects     []runtime.Object
+		storedGeneration   int64
+		atomic             bool
+		expectedGeneration int64
+		expectedVerbs      []string
+	}{
+		"stored-generation": {
+			storedGeneration:   5,
+			expectedGeneration: 6,
+			expectedVerbs:      []string{"create", "create"},
+		},
+		"slice-generation": {
+			initialObjects:     []runtime.Object{legacySlice},
+			expectedGeneration: 2,
+			expectedVerbs:      []string{"delete", "create", "create"},
+		},
+		"atomic": {
+			initialObjects:     []runtime.Object{legacySlice},
+			atomic:             true,
+			expectedGeneration: 2,
+			expectedVerbs:      []string{"create", "create", "delete"},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			_, ctx := ktesting.NewTestContext(t)
+			kubeClient := createTestClient(features{}, metav1.Now(), tc.initialObjects...)
+			store := memoryGenerationStore{}
+			if tc.storedGeneration > 0 {
+				store[poolName] = tc.storedGeneration
+			}
+			var queue workqueue.Mock[string]
+			ctrl, err := newController(ctx, Options{
+				DriverName:              driverName,
+				KubeClient:              kubeClient,
+				Resources:               resources,
+				Queue:                   &queue,
+				GenerationStore:         store,
+				AtomicGenerationUpdates: tc.atomic,
+				ErrorHandler: func(ctx context.Context, err error, msg string) {
+					assert.NoError(t, err, msg)
+				},
+			})
+			require.NoError(t, err, "unexpected controller creation error")
+			defer ctrl.Stop()
+			kubeClient.ClearActions()
+			ctrl.run(ctx)
+
+			var verbs []string
+			for _, action := range kubeClient.Actions() {
+				if action.GetResource().Resource == "resourceslices" && (action.GetVerb() == "create" || action.GetVerb() == "delete") {
+					verbs = append(verbs, action.GetVerb())
+				}
+			}
+			assert.Equal(t, tc.expectedVerbs, verbs)
+
+			slices, err := kubeClient.ResourceV1().ResourceSlices().List(ctx, metav1.ListOptions{})
+			require.NoError(t, err, "list resource slices")
+			require.Len(t, slices.Items, 2)
+			for _, slice := range slices.Items {
+				assert.Equal(t, tc.expectedGeneration, slice.Spec.Pool.Generation, slice.Name)
+			}
+			assert.Equal(t, tc.expectedGeneration, store[poolName], "stored generation")
+		})
+	}
+}
+
+func TestControllerUpdateDelay(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	const delay = 10 * time.Second
+	pool := func(deviceNames ...string) Pool {
+		var devices []resourceapi.Device
+		for _, name := range deviceNames {
+			devices = append(devices, resourceapi.Device{Name: name})
+		}
+		return Pool{AllNodes: true, Slices: []Slice{{Devices: devices}}}
+	}
+	var queue workqueue.Mock[string]
+	ctrl, err := newController(ctx, Options{
+		DriverName: "driver.example.com",
+		KubeClient: createTestClient(features{}, metav1.Now()),
+		Resources: &DriverResources{
+			Pools: map[string]Pool{
+				"pool-a": pool("dev-a", "dev-b"),
+				"pool-b": pool("dev-c"),
+			},
+		},
+		Queue:       &queue,
+		UpdateDelay: ptr.To(delay),
+	})
+	require.NoError(t, err, "unexpected controller creation error")
+	defer ctrl.Stop()
+
+	// New pools get synced after the delay.
+	state := queue.State()
+	assert.Empty(t, state.Ready)
+	assert.ElementsMatch(t, []workqueue.MockDelayedItem[string]{
+		{Item: "pool-a", Duration: delay},
+		{Item: "pool-b", Duration: delay},
+	}, state.Later)
+
+	// Removing a device is urgent, adding one is not.
+	ctrl.Update(&DriverResources{
+		Pools: map[string]Pool{
+			"pool-a": pool("dev-a"),
+			"pool-b": pool("dev-c", "dev-d"),
+		},
+	})
+	state = queue.State()
+	assert.Equal(t, []string{"pool-a"}, state.Ready)
+	assert.Contains(t, state.Later, workqueue.MockDelayedItem[string]{Item: "pool-b", Duration: delay})
+
+	// Removing a pool is urgent, too.
+	ctrl.RemovePool("pool-b")
+	assert.Equal(t, []string{"pool-a", "pool-b"}, queue.State().Ready)
+}
+
+func TestControllerServerSideApply(t *testing.T) {
+	const (
+		driverName = "driver.example.com"
+		poolName   = "pool"
+	)
+	sliceName := applySliceName(encodeIndex(0, getIndexLength(1))+nameSeparator+driverName+nameSeparator, poolName)
+	resources := &DriverResources{
+		Pools: map[string]Pool{
+			poolName: {AllNodes: true, Slices: []Slice{{Devices: []resourceapi.Device{{Name: "dev-a"}}}}},
+		},
+	}
+
+	t.Run("apply", func(t *testing.T) {
+		_, ctx := ktesting.NewTestContext(t)
+		kubeClient := fake.NewClientset()
+		var queue workqueue.Mock[string]
+		var controllerErrors []error
+		ctrl, err := newController(ctx, Options{
+			DriverName:      driverName,
+			KubeClient:      kubeClient,
+			Resources:       resources,
+			Queue:           &queue,
+			ServerSideApply: true,
+			ErrorHandler: func(ctx context.Context, err error, msg string) {
+				controllerErrors = append(controllerErrors, fmt.Errorf("%s: %w", msg, err))
+			},
+		})
+		require.NoError(t, err, "unexpected controller creation error")
+		defer ctrl.Stop()
+		ctrl.run(ctx)
+		require.Empty(t, controllerErrors)
+
+		slice, err := kubeClient.ResourceV1().ResourceSlices().Get(ctx, sliceName, metav1.GetOptions{})
+		require.NoError(t, err, "get applied slice")
+		assert.Equal(t, []resourceapi.Device{{Name: "dev-a"}}, slice.Spec.Devices)
+		var managers []string
+		for _, entry := range slice.ManagedFields {
+			managers = append(managers, entry.Manager)
+		}
+		assert.Contains(t, managers, DefaultFieldManagerPrefix+driverName)
+		assert.Equal(t, Stats{NumCreates: 1}, ctrl.GetStats())
+		_, ok := ctrl.lastAddByPool[poolName]
+		assert.False(t, ok, "no re-sync for mutation cache needed")
+		assert.IsType(t, indexerWithoutMutations{}, ctrl.sliceStore, "no mutation cache")
+
+		_, err = ctrl.UpdatePool(poolName, Pool{AllNodes: true, Slices: []Slice{{Devices: []resourceapi.Device{{Name: "dev-b"}}}}})
+		require.NoError(t, err, "update pool")
+		ctrl.run(ctx)
+		require.Empty(t, controllerErrors)
+		slice, err = kubeClient.ResourceV1().ResourceSlices().Get(ctx, sliceName, metav1.GetOptions{})
+		require.NoError(t, err, "get applied slice")
+		assert.Equal(t, []resourceapi.Device{{Name: "dev-b"}}, slice.Spec.Devices)
+		assert.Equal(t, Stats{NumCreates: 1, NumUpdates: 1}, ctrl.GetStats())
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		_, ctx := ktesting.NewTestContext(t)
+		kubeClient := fake.NewClientset()
+		otherSlice := &resourceapi.ResourceSlice{
+			TypeMeta:   metav1.TypeMeta{APIVersion: resourceapi.SchemeGroupVersion.String(), Kind: "ResourceSlice"},
+			ObjectMeta: metav1.ObjectMeta{Name: sliceName},
+			Spec: resourceapi.ResourceSliceSpec{
+				Driver:   driverName,
+				Pool:     resourceapi.ResourcePool{Name: poolName, Generation: 1, ResourceSliceCount: 1},
+				AllNodes: ptr.To(true),
+				Devices:  []resourceapi.Device{{Name: "dev-x"}},
+			},
+		}
+		data, err := json.Marshal(otherSlice)
+		require.NoError(t, err)
+		_, err = kubeClient.ResourceV1().ResourceSlices().Patch(ctx, sliceName, types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: "admin"})
+		require.NoError(t, err, "apply as admin")
+
+		var queue workqueue.Mock[string]
+		var controllerErrors []error
+		ctrl, err := newController(ctx, Options{
+			DriverName:      driverName,
+			KubeClient:      kubeClient,
+			Resources:       resources,
+			Queue:           &queue,
+			ServerSideApply: true,
+			ErrorHandler: func(ctx context.Context, err error, msg string) {
+				controllerErrors = append(controllerErrors, fmt.Errorf("%s: %w", msg, err))
+			},
+		})
+		require.NoError(t, err, "unexpected controller creation error")
+		defer ctrl.Stop()
+		// The informer must have seen the existing slice before syncing.
+		require.Eventually(t, func() bool {
+			objs, err := ctrl.sliceStore.ByIndex(poolNameIndex, poolName)
+			return err == nil && len(objs) == 1
+		}, time.Minute, time.Millisecond)
+		ctrl.run(ctx)
+
+		require.NotEmpty(t, controllerErrors, "expected conflict")
+		assert.True(t, apierrors.IsConflict(controllerErrors[0]), "expected conflict error, got: %v", controllerErrors[0])
+		assert.Contains(t, controllerErrors[0].Error(), "another field manager owns fields of ResourceSlice")
+		status, _ := ctrl.PoolStatus(poolName)
+		assert.False(t, status.Published, "pool published")
+	})
+}
+
+// TestControllerDryRun verifies that dry-run mode computes the operations
+// without performing them.
+func TestControllerDryRun(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	const driverName = "driver.example.com"
+	existingSlice := func(name, poolName string, devices ...resourceapi.Device) *resourceapi.ResourceSlice {
+		return &resourceapi.ResourceSlice{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: resourceapi.ResourceSliceSpec{
+				Driver:   driverName,
+				Pool:     resourceapi.ResourcePool{Name: poolName, Generation: 1, ResourceSliceCount: 1},
+				AllNodes: ptr.To(true),
+				Devices:  devices,
+			},
+		}
+	}
+	prefix := encodeIndex(0, getIndexLength(1)) + nameSeparator + driverName + nameSeparator
+	kubeClient := fake.NewSimpleClientset(
+		existingSlice(prefix+"a", "pool", resourceapi.Device{Name: "dev-a"}, resourceapi.Device{Name: "dev-b"}),
+		existingSlice(prefix+"b", "old-pool", resourceapi.Device{Name: "dev-x"}),
+	)
+	modifiedDevice := resourceapi.Device{
+		Name: "dev-a",
+		Attributes: map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			"model": {StringValue: ptr.To("a100")},
+		},
+	}
+	var queue workqueue.Mock[string]
+	var controllerErrors []error
+	ctrl, err := newController(ctx, Options{
+		DriverName: driverName,
+		KubeClient: kubeClient,
+		Resources: &DriverResources{
+			Pools: map[string]Pool{
+				"pool":     {AllNodes: true, Slices: []Slice{{Devices: []resourceapi.Device{modifiedDevice, {Name: "dev-c"}}}}},
+				"new-pool": {AllNodes: true, Slices: []Slice{{Devices: []resourceapi.Device{{Name: "dev-d"}}}}},
+			},
+		},
+		Queue:  &queue,
+		DryRun: true,
+		ErrorHandler: func(ctx context.Context, err error, msg string) {
+			controllerErrors = append(controllerErrors, fmt.Errorf("%s: %w", msg, err))
+		},
+	})
+	require.NoError(t, err, "unexpected controller creation error")
+	defer ctrl.Stop()
+	require.Eventually(t, func() bool {
+		pool, _ := ctrl.sliceStore.ByIndex(poolNameIndex, "pool")
+		oldPool, _ := ctrl.sliceStore.ByIndex(poolNameIndex, "old-pool")
+		return len(pool) == 1 && len(oldPool) == 1
+	}, time.Minute, time.Millisecond)
+	// The old pool is unknown to the controller, so it has to be synced explicitly.
+	queue.Add("old-pool")
+	ctrl.run(ctx)
+	require.Empty(t, controllerErrors)
+	require.NoError(t, ctrl.WaitForPublished(ctx))
+
+	for _, action := range kubeClient.Actions() {
+		switch action.GetVerb() {
+		case "create", "update", "patch", "delete":
+			t.Errorf("unexpected %s in dry-run mode", action.GetVerb())
+		}
+	}
+	assert.Equal(t, Stats{}, ctrl.GetStats())
+
+	plan := ctrl.Plan()
+	require.Len(t, plan, 3)
+
+	poolPlan := plan["pool"]
+	assert.Equal(t, int64(1), poolPlan.Generation)
+	require.Len(t, poolPlan.Operations, 1)
+	update := poolPlan.Operations[0]
+	assert.Equal(t, OperationUpdate, update.Type)
+	assert.Equal(t, 0, update.SliceIndex)
+	assert.Equal(t, prefix+"a", update.Current.Name)
+	assert.NotEmpty(t, update.Diff)
+	require.Len(t, update.Devices, 3)
+	assert.Equal(t, DeviceChange{Name: "dev-a", Type: DeviceModified, Diff: update.Devices[0].Diff}, update.Devices[0])
+	assert.Contains(t, update.Devices[0].Diff, "a100")
+	assert.Equal(t, DeviceChange{Name: "dev-c", Type: DeviceAdded}, update.Devices[1])
+	assert.Equal(t, DeviceChange{Name: "dev-b", Type: DeviceRemoved}, update.Devices[2])
+
+	newPoolPlan := plan["new-pool"]
+	assert.Equal(t, int64(1), newPoolPlan.Generation)
+	require.Len(t, newPoolPlan.Operations, 1)
+	assert.Equal(t, OperationCreate, newPoolPlan.Operations[0].Type)
+	assert.Equal(t, prefix, newPoolPlan.Operations[0].Desired.GenerateName)
+	assert.Equal(t, []DeviceChange{{Name: "dev-d", Type: DeviceAdded}}, newPoolPlan.Operations[0].Devices)
+
+	oldPoolPlan := plan["old-pool"]
+	require.Len(t, oldPoolPlan.Operations, 1)
+	assert.Equal(t, OperationDelete, oldPoolPlan.Operations[0].Type)
+	assert.Equal(t, prefix+"b", oldPoolPlan.Operations[0].Current.Name)
+	assert.Equal(t, []DeviceChange{{Name: "dev-x", Type: DeviceRemoved}}, oldPoolPlan.Operations[0].Devices)
+}
+
+// TestControllerDryRunOrder verifies that the planned operations are in
+// the same order as the ones performed by syncPool.
+func TestControllerDryRunOrder(t *testing.T) {
+	const driverName = "driver.example.com"
+	existingSlice := func(name string, devices ...resourceapi.Device) *resourceapi.ResourceSlice {
+		return &resourceapi.ResourceSlice{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: resourceapi.ResourceSliceSpec{
+				Driver:   driverName,
+				Pool:     resourceapi.ResourcePool{Name: "pool", Generation: 1, ResourceSliceCount: 2},
+				AllNodes: ptr.To(true),
+				Devices:  devices,
+			},
+		}
+	}
+	prefix := encodeIndex(0, getIndexLength(2)) + nameSeparator + driverName + nameSeparator
+
+	testcases := map[string]struct {
+		atomic           bool
+		expectOperations []OperationType
+	}{
+		"default": {
+			expectOperations: []OperationType{OperationDelete, OperationUpdate, OperationCreate},
+		},
+		"atomic": {
+			atomic:           true,
+			expectOperations: []OperationType{OperationUpdate, OperationCreate, OperationDelete},
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			_, ctx := ktesting.NewTestContext(t)
+			kubeClient := fake.NewSimpleClientset(
+				existingSlice(prefix+"a", resourceapi.Device{Name: "dev-a"}),
+				// Not a valid name, therefore obsolete.
+				existingSlice("stale", resourceapi.Device{Name: "dev-x"}),
+			)
+			var queue workqueue.Mock[string]
+			var controllerErrors []error
+			ctrl, err := newController(ctx, Options{
+				DriverName: driverName,
+				KubeClient: kubeClient,
+				Resources: &DriverResources{
+					Pools: map[string]Pool{
+						"pool": {AllNodes: true, Slices: []Slice{
+							{Devices: []resourceapi.Device{{Name: "dev-a"}}},
+							{Devices: []resourceapi.Device{{Name: "dev-b"}}},
+						}},
+					},
+				},
+				Queue:                   &queue,
+				DryRun:                  true,
+				AtomicGenerationUpdates: tc.atomic,
+				ErrorHandler: func(ctx context.Context, err error, msg string) {
+					controllerErrors = append(controllerErrors, fmt.Errorf("%s: %w", msg, err))
+				},
+			})
+			require.NoError(t, err, "unexpected controller creation error")
+			defer ctrl.Stop()
+			require.Eventually(t, func() bool {
+				pool, _ := ctrl.sliceStore.ByIndex(poolNameIndex, "pool")
+				return len(pool) == 2
+			}, time.Minute, time.Millisecond)
+			ctrl.run(ctx)
+			require.Empty(t, controllerErrors)
+
+			poolPlan := ctrl.Plan()["pool"]
+			assert.Equal(t, int64(2), poolPlan.Generation)
+			var operations []OperationType
+			for _, operation := range poolPlan.Operations {
+				operations = append(operations, operation.Type)
+			}
+			assert.Equal(t, tc.expectOperations, operations)
+		})
+	}
+}
+
 func joinErrors(errors []string) string {
 	return strings.Join(errors, "\n  ")
 }
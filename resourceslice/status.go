@@ -0,0 +1,308 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceslice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PoolStatus describes how far the controller got with publishing one pool.
+type PoolStatus struct {
+	// DesiredGeneration is the [Pool.Generation] requested by the driver.
+	// Zero if the driver leaves choosing the generation to the controller.
+	DesiredGeneration int64
+
+	// PublishedGeneration is the pool generation in the ResourceSlices
+	// after the most recent successful synchronization. Only valid if
+	// LastSyncTime is not zero.
+	PublishedGeneration int64
+
+	// Published is true if the ResourceSlices in the API server
+	// reflect the most recent desired state of the pool.
+	Published bool
+
+	// LastError is the error of the most recent failed synchronization,
+	// nil if the most recent synchronization succeeded.
+	LastError error
+
+	// LastSyncTime is the time of the most recent successful synchronization.
+	LastSyncTime time.Time
+}
+
+type poolStatus struct {
+	publishedGeneration int64
+	lastError           error
+	lastSyncTime        time.Time
+	// syncedVersion is the version of the desired state which was
+	// published by the most recent successful synchronization.
+	syncedVersion int64
+	// invalidVersion is the version of the desired state which failed
+	// validation or the fallback policy, zero if the most recent
+	// synchronization didn't fail like that. Such a failure is
+	// permanent, the same desired state doesn't get synced again.
+	invalidVersion int64
+}
+
+// PoolStatus returns the status of one pool in the current desired state.
+// False if the pool is unknown.
+func (c *Controller) PoolStatus(poolName string) (PoolStatus, bool) {
+	c.mutex.RLock()
+	pool, ok := c.resources.Pools[poolName]
+	version := c.desiredVersions[poolName]
+	c.mutex.RUnlock()
+	if !ok {
+		return PoolStatus{}, false
+	}
+
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	return c.poolStatus(pool, version, c.poolStatuses[poolName]), true
+}
+
+// PoolStatuses returns the status of all pools in the current desired state.
+func (c *Controller) PoolStatuses() map[string]PoolStatus {
+	c.mutex.RLock()
+	resources := c.resources
+	versions := make(map[string]int64, len(resources.Pools))
+	for poolName := range resources.Pools {
+		versions[poolName] = c.desiredVersions[poolName]
+	}
+	c.mutex.RUnlock()
+
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	statuses := make(map[string]PoolStatus, len(resources.Pools))
+	for poolName, pool := range resources.Pools {
+		statuses[poolName] = c.poolStatus(pool, versions[poolName], c.poolStatuses[poolName])
+	}
+	return statuses
+}
+
+func (c *Controller) poolStatus(pool Pool, version int64, status *poolStatus) PoolStatus {
+	result := PoolStatus{
+		DesiredGeneration: pool.Generation,
+	}
+	if status != nil {
+		result.PublishedGeneration = status.publishedGeneration
+		result.Published = status.syncedVersion >= version && !status.lastSyncTime.IsZero()
+		result.LastError = status.lastError
+		result.LastSyncTime = status.lastSyncTime
+	}
+	return result
+}
+
+// PublishedGeneration returns the generation of the pool as published
+// by the most recent successful synchronization of the pool. False if
+// the pool has not been published (yet).
+func (c *Controller) PublishedGeneration(poolName string) (int64, bool) {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	status, ok := c.poolStatuses[poolName]
+	if !ok || status.lastSyncTime.IsZero() {
+		return 0, false
+	}
+	return status.publishedGeneration, true
+}
+
+// WaitForPoolVersion blocks until the ResourceSlices of the pool reflect
+// the desired state with the given version or a more recent one. The
+// version is the one returned by [Controller.UpdatePool]. If the pool
+// got removed in the meantime, it waits for the removal instead.
+//
+// It returns the context error when the context gets canceled first.
+// It returns the error immediately when the desired state cannot be
+// published because of a validation or fallback policy error.
+func (c *Controller) WaitForPoolVersion(ctx context.Context, poolName string, version int64) error {
+	return c.waitFor(ctx, func() (bool, error) {
+		c.mutex.RLock()
+		_, desired := c.resources.Pools[poolName]
+		c.mutex.RUnlock()
+
+		c.statusMutex.Lock()
+		defer c.statusMutex.Unlock()
+		status := c.poolStatuses[poolName]
+		if !desired {
+			return status == nil, nil
+		}
+		if status != nil && status.invalidVersion >= version {
+			return false, fmt.Errorf("pool %q: %w", poolName, status.lastError)
+		}
+		return status != nil && !status.lastSyncTime.IsZero() && status.syncedVersion >= version, nil
+	}, fmt.Sprintf("version %d of pool %q", version, poolName))
+}
+
+// WaitForPublished blocks until the ResourceSlices of all pools reflect
+// the current desired state, including the removal of pools. If the
+// desired state changes while waiting, then it waits for the new state.
+// This can be used to report readiness only once ResourceSlices are
+// available.
+//
+// It returns the context error when the context gets canceled first.
+// The error includes the most recent synchronization error, if there was
+// one. Validation and fallback policy errors are returned immediately
+// because those don't get retried until the desired state changes.
+func (c *Controller) WaitForPublished(ctx context.Context) error {
+	var lastErr error
+	err := c.waitFor(ctx, func() (bool, error) {
+		c.mutex.RLock()
+		pools := c.resources.Pools
+		versions := make(map[string]int64, len(c.desiredVersions))
+		for poolName, version := range c.desiredVersions {
+			versions[poolName] = version
+		}
+		c.mutex.RUnlock()
+
+		c.statusMutex.Lock()
+		defer c.statusMutex.Unlock()
+		lastErr = nil
+		published := true
+		for poolName, version := range versions {
+			status := c.poolStatuses[poolName]
+			if status != nil && status.lastError != nil {
+				lastErr = fmt.Errorf("pool %q: %w", poolName, status.lastError)
+				if status.invalidVersion >= version {
+					return false, lastErr
+				}
+			}
+			if _, ok := pools[poolName]; !ok {
+				// Removed pools have no status once they are gone.
+				if status != nil {
+					published = false
+				}
+				continue
+			}
+			if status == nil || status.lastSyncTime.IsZero() || status.syncedVersion < version {
+				published = false
+			}
+		}
+		return published, nil
+	}, "publishing all pools")
+	if err != nil && lastErr != nil && !errors.Is(err, lastErr) {
+		return fmt.Errorf("%w (last error: %w)", err, lastErr)
+	}
+	return err
+}
+
+// waitFor checks the condition each time that the status changes.
+// An error from the condition stops waiting.
+func (c *Controller) waitFor(ctx context.Context, condition func() (bool, error), what string) error {
+	for {
+		c.statusMutex.Lock()
+		changed := c.statusChanged
+		c.statusMutex.Unlock()
+		done, err := condition()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %s: %w", what, context.Cause(ctx))
+		case <-changed:
+		}
+	}
+}
+
+// poolChanged must be called with c.mutex locked after
+// changing the desired state of the pool. The pool gets
+// synced after the UpdateDelay.
+func (c *Controller) poolChanged(poolName string) {
+	c.poolChangedAfter(poolName, c.updateDelay)
+}
+
+// poolChangedAfter is like poolChanged with a custom delay.
+func (c *Controller) poolChangedAfter(poolName string, delay time.Duration) {
+	if c.desiredVersions == nil {
+		c.desiredVersions = make(map[string]int64)
+	}
+	c.lastVersion++
+	c.desiredVersions[poolName] = c.lastVersion
+	if delay > 0 {
+		c.queue.AddAfter(poolName, delay)
+	} else {
+		c.queue.Add(poolName)
+	}
+}
+
+// poolPublished records the outcome of a successful syncPool.
+func (c *Controller) poolPublished(poolName string, version, generation int64) {
+	c.updateStatus(poolName, func(status *poolStatus) {
+		status.publishedGeneration = generation
+		status.syncedVersion = version
+		status.lastError = nil
+		status.invalidVersion = 0
+		status.lastSyncTime = time.Now()
+	})
+}
+
+// poolFailed records the outcome of a failed syncPool which gets retried.
+func (c *Controller) poolFailed(poolName string, err error) {
+	c.updateStatus(poolName, func(status *poolStatus) {
+		status.lastError = err
+		status.invalidVersion = 0
+	})
+}
+
+// poolInvalid records that syncPool cannot publish the given version
+// of the desired state. It doesn't get retried.
+func (c *Controller) poolInvalid(poolName string, version int64, err error) {
+	c.updateStatus(poolName, func(status *poolStatus) {
+		status.lastError = err
+		status.invalidVersion = version
+	})
+}
+
+// poolRemoved records that all ResourceSlices of a pool were removed
+// or, for a pool that was never desired, are not going to be removed.
+// The status and the desired version of the pool are forgotten, unless
+// the pool was added again since the version that was synced.
+func (c *Controller) poolRemoved(poolName string, version int64) {
+	c.mutex.Lock()
+	_, desired := c.resources.Pools[poolName]
+	if !desired && c.desiredVersions[poolName] == version {
+		delete(c.desiredVersions, poolName)
+	}
+	c.mutex.Unlock()
+
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	delete(c.poolStatuses, poolName)
+	c.notifyStatusChangedLocked()
+}
+
+func (c *Controller) updateStatus(poolName string, update func(status *poolStatus)) {
+	c.statusMutex.Lock()
+	defer c.statusMutex.Unlock()
+	status := c.poolStatuses[poolName]
+	if status == nil {
+		status = &poolStatus{}
+		c.poolStatuses[poolName] = status
+	}
+	update(status)
+	c.notifyStatusChangedLocked()
+}
+
+// notifyStatusChangedLocked wakes up all goroutines blocked in waitFor.
+func (c *Controller) notifyStatusChangedLocked() {
+	close(c.statusChanged)
+	c.statusChanged = make(chan struct{})
+}
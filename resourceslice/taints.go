@@ -0,0 +1,153 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourceslice
+
+import (
+	"slices"
+
+	resourceapi "k8s.io/api/resource/v1"
+)
+
+// SetAllDeviceTaints publishes the given taints for all devices of all
+// pools, in addition to the taints in the desired state. Each call
+// replaces the taints from the previous call, so calling it without
+// taints removes them again.
+//
+// A typical use is a NoSchedule taint which gets set during a graceful
+// shutdown of the driver, combined with [Controller.WaitForPublished].
+// When the driver starts again, a new controller does not know about
+// the taint and removes it.
+//
+// A taint which has the same key and effect as a taint in the
+// desired state of a device is not added to that device. The result
+// must not exceed resourceapi.DeviceTaintsMaxLength, otherwise
+// the apiserver rejects the ResourceSlice.
+func (c *Controller) SetAllDeviceTaints(taints ...resourceapi.DeviceTaint) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(taints) == 0 && len(c.allDeviceTaints) == 0 {
+		return
+	}
+	c.allDeviceTaints = cloneTaints(taints)
+	for poolName := range c.resources.Pools {
+		c.poolChanged(poolName)
+	}
+}
+
+// SetDeviceTaints publishes the given taints for one device, in addition
+// to the taints in the desired state and those set with
+// [Controller.SetAllDeviceTaints]. Each call replaces the taints
+// from the previous call for the same device, so calling it
+// without taints removes them again.
+//
+// This can be used to reflect the health of individual devices:
+// a NoSchedule or NoExecute taint gets set when the driver detects
+// that a device is unhealthy and gets removed once it has recovered.
+//
+// The taints are remembered even if the pool or the device is not
+// part of the desired state (yet).
+func (c *Controller) SetDeviceTaints(poolName, deviceName string, taints ...resourceapi.DeviceTaint) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, hasTaints := c.deviceTaints[poolName][deviceName]
+	if len(taints) == 0 && !hasTaints {
+		return
+	}
+
+	// syncPool reads the map without holding the mutex,
+	// so it must be replaced instead of modified in place.
+	deviceTaints := make(map[string]map[string][]resourceapi.DeviceTaint, len(c.deviceTaints)+1)
+	for name, devices := range c.deviceTaints {
+		deviceTaints[name] = devices
+	}
+	devices := make(map[string][]resourceapi.DeviceTaint, len(deviceTaints[poolName])+1)
+	for name, t := range deviceTaints[poolName] {
+		devices[name] = t
+	}
+	if len(taints) == 0 {
+		delete(devices, deviceName)
+	} else {
+		devices[deviceName] = cloneTaints(taints)
+	}
+	if len(devices) == 0 {
+		delete(deviceTaints, poolName)
+	} else {
+		deviceTaints[poolName] = devices
+	}
+	c.deviceTaints = deviceTaints
+	if _, ok := c.resources.Pools[poolName]; ok {
+		c.poolChanged(poolName)
+	}
+}
+
+// DeviceTaints returns the taints set with [Controller.SetDeviceTaints]
+// for one device.
+func (c *Controller) DeviceTaints(poolName, deviceName string) []resourceapi.DeviceTaint {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return cloneTaints(c.deviceTaints[poolName][deviceName])
+}
+
+func cloneTaints(taints []resourceapi.DeviceTaint) []resourceapi.DeviceTaint {
+	if len(taints) == 0 {
+		return nil
+	}
+	result := make([]resourceapi.DeviceTaint, len(taints))
+	for i := range taints {
+		taints[i].DeepCopyInto(&result[i])
+	}
+	roundDeviceTaintTimeAdded(result)
+	return result
+}
+
+// addTaints returns the pool with additional taints. Slices and
+// devices which get modified are copied, everything else is shared
+// with the input.
+func addTaints(pool Pool, allDeviceTaints []resourceapi.DeviceTaint, deviceTaints map[string][]resourceapi.DeviceTaint) Pool {
+	if len(allDeviceTaints) == 0 && len(deviceTaints) == 0 {
+		return pool
+	}
+	pool.Slices = slices.Clone(pool.Slices)
+	for i := range pool.Slices {
+		var devices []resourceapi.Device
+		for j := range pool.Slices[i].Devices {
+			device := &pool.Slices[i].Devices[j]
+			taints := slices.Concat(allDeviceTaints, deviceTaints[device.Name])
+			if len(taints) == 0 {
+				continue
+			}
+			if devices == nil {
+				devices = slices.Clone(pool.Slices[i].Devices)
+			}
+			devices[j].Taints = slices.Clone(device.Taints)
+			for _, taint := range taints {
+				if slices.ContainsFunc(devices[j].Taints, func(existing resourceapi.DeviceTaint) bool {
+					return existing.Key == taint.Key && existing.Effect == taint.Effect
+				}) {
+					continue
+				}
+				devices[j].Taints = append(devices[j].Taints, taint)
+			}
+		}
+		if devices != nil {
+			pool.Slices[i].Devices = devices
+		}
+	}
+	return pool
+}
@@ -0,0 +1,81 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"time"
+
+	resourceslicemetrics "k8s.io/dynamic-resource-allocation/resourceslice/metrics"
+)
+
+// DefaultName is the default for Options.Name.
+const DefaultName = "default"
+
+// trackerMetrics updates the metrics of one tracker instance.
+// All methods can be called for nil, which is used by trackers
+// which don't report metrics.
+type trackerMetrics struct {
+	name string
+}
+
+func newTrackerMetrics(name string) *trackerMetrics {
+	if name == "" {
+		name = DefaultName
+	}
+	return &trackerMetrics{name: name}
+}
+
+func (m *trackerMetrics) addQueuedEvents(delta float64) {
+	if m == nil {
+		return
+	}
+	resourceslicemetrics.ResourceSliceTrackerEventQueueLength.WithLabelValues(m.name).Add(delta)
+}
+
+func (m *trackerMetrics) observeHandler(eventType string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	resourceslicemetrics.ResourceSliceTrackerHandlerDuration.WithLabelValues(m.name, eventType).Observe(duration.Seconds())
+}
+
+func (m *trackerMetrics) addPatchedSlices(delta float64) {
+	if m == nil {
+		return
+	}
+	resourceslicemetrics.ResourceSliceTrackerPatchedSlices.WithLabelValues(m.name).Add(delta)
+}
+
+func (m *trackerMetrics) addDeviceTaintRules(delta float64) {
+	if m == nil {
+		return
+	}
+	resourceslicemetrics.ResourceSliceTrackerDeviceTaintRules.WithLabelValues(m.name).Add(delta)
+}
+
+// delete removes the metrics of a stopped tracker.
+func (m *trackerMetrics) delete() {
+	if m == nil {
+		return
+	}
+	resourceslicemetrics.ResourceSliceTrackerEventQueueLength.DeleteLabelValues(m.name)
+	resourceslicemetrics.ResourceSliceTrackerPatchedSlices.DeleteLabelValues(m.name)
+	resourceslicemetrics.ResourceSliceTrackerDeviceTaintRules.DeleteLabelValues(m.name)
+	for _, eventType := range []string{"add", "update", "delete"} {
+		resourceslicemetrics.ResourceSliceTrackerHandlerDuration.DeleteLabelValues(m.name, eventType)
+	}
+}
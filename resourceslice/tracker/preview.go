@@ -0,0 +1,159 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	resourceapi "k8s.io/api/resource/v1"
+	resourcebetaapi "k8s.io/api/resource/v1beta2"
+	labels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	resourcelisters "k8s.io/client-go/listers/resource/v1"
+	"k8s.io/dynamic-resource-allocation/resourceclaim"
+	"k8s.io/dynamic-resource-allocation/structured/schedulerapi"
+	"k8s.io/utils/ptr"
+)
+
+// DeviceTaintRulePreview is the result of [Tracker.PreviewDeviceTaintRule].
+type DeviceTaintRulePreview struct {
+	// Devices contains the devices which the rule would taint,
+	// sorted by their ID.
+	Devices []schedulerapi.DeviceID
+
+	// Claims contains the allocated ResourceClaims which use at least
+	// one of those devices without tolerating the taint, sorted by
+	// namespace and name. Only set for the NoExecute effect because
+	// other effects do not affect claims which are already allocated.
+	Claims []*resourceapi.ResourceClaim
+
+	// Pods contains the pods which have reserved one of those claims
+	// and thus would get evicted, sorted by namespace and name.
+	Pods []types.NamespacedName
+}
+
+// PreviewDeviceTaintRule determines what would happen if the rule
+// was created, without creating it. The devices are matched against
+// the current ResourceSlices in the same way as for the patched
+// ResourceSlices provided by the tracker. The claim lister is
+// optional. Without it, no claims are checked.
+//
+// The result does not take into account whether a device is
+// already tainted by some other rule or by its driver, nor
+// the time span of the rule.
+func (t *Tracker) PreviewDeviceTaintRule(rule *resourcebetaapi.DeviceTaintRule, claimLister resourcelisters.ResourceClaimLister) (*DeviceTaintRulePreview, error) {
+	devices, err := t.matchingDevices(rule)
+	if err != nil {
+		return nil, err
+	}
+	preview := &DeviceTaintRulePreview{}
+	preview.Devices = devices.UnsortedList()
+	slices.SortFunc(preview.Devices, func(a, b schedulerapi.DeviceID) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	taint := taintFromRule(rule)
+	if claimLister == nil || devices.Len() == 0 || taint.Effect != resourceapi.DeviceTaintEffectNoExecute {
+		return preview, nil
+	}
+	claims, err := claimLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("list ResourceClaims: %w", err)
+	}
+	for _, claim := range claims {
+		if !claimAffected(claim, devices, taint) {
+			continue
+		}
+		preview.Claims = append(preview.Claims, claim)
+		for _, consumer := range claim.Status.ReservedFor {
+			if consumer.APIGroup == "" && consumer.Resource == "pods" {
+				preview.Pods = append(preview.Pods, types.NamespacedName{Namespace: claim.Namespace, Name: consumer.Name})
+			}
+		}
+	}
+	slices.SortFunc(preview.Claims, func(a, b *resourceapi.ResourceClaim) int {
+		return strings.Compare(a.Namespace+"/"+a.Name, b.Namespace+"/"+b.Name)
+	})
+	slices.SortFunc(preview.Pods, func(a, b types.NamespacedName) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	preview.Pods = slices.Compact(preview.Pods)
+	return preview, nil
+}
+
+// matchingDevices returns the devices in the unpatched ResourceSlices
+// which are selected by the rule.
+func (t *Tracker) matchingDevices(rule *resourcebetaapi.DeviceTaintRule) (sets.Set[schedulerapi.DeviceID], error) {
+	resourceSlices, err := t.resourceSliceLister.List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("list ResourceSlices: %w", err)
+	}
+	devices := sets.New[schedulerapi.DeviceID]()
+	for _, slice := range resourceSlices {
+		for _, device := range slice.Spec.Devices {
+			if ruleSelects(rule, slice.Spec.Driver, slice.Spec.Pool.Name, device.Name) {
+				devices.Insert(schedulerapi.MakeDeviceID(slice.Spec.Driver, slice.Spec.Pool.Name, device.Name))
+			}
+		}
+	}
+	return devices, nil
+}
+
+// claimAffected returns true if the claim is allocated and uses
+// one of the devices without tolerating the taint.
+func claimAffected(claim *resourceapi.ResourceClaim, devices sets.Set[schedulerapi.DeviceID], taint resourceapi.DeviceTaint) bool {
+	if claim.Status.Allocation == nil {
+		return false
+	}
+	for _, result := range claim.Status.Allocation.Devices.Results {
+		if !devices.Has(schedulerapi.MakeDeviceID(result.Driver, result.Pool, result.Device)) {
+			continue
+		}
+		if !slices.ContainsFunc(result.Tolerations, func(toleration resourceapi.DeviceToleration) bool {
+			return resourceclaim.ToleratesTaint(toleration, taint)
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleSelects returns true if the rule applies to the device.
+func ruleSelects(rule *resourcebetaapi.DeviceTaintRule, driver, pool, device string) bool {
+	deviceSelector := rule.Spec.DeviceSelector
+	if deviceSelector == nil {
+		return true
+	}
+	return ptr.Deref(deviceSelector.Driver, driver) == driver &&
+		ptr.Deref(deviceSelector.Pool, pool) == pool &&
+		ptr.Deref(deviceSelector.Device, device) == device
+}
+
+// taintFromRule converts the taint of the rule.
+//
+// TODO: remove conversion once taint is already in the right API package.
+func taintFromRule(rule *resourcebetaapi.DeviceTaintRule) resourceapi.DeviceTaint {
+	return resourceapi.DeviceTaint{
+		Key:       rule.Spec.Taint.Key,
+		Value:     rule.Spec.Taint.Value,
+		Effect:    resourceapi.DeviceTaintEffect(rule.Spec.Taint.Effect),
+		TimeAdded: rule.Spec.Taint.TimeAdded,
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resourceapi "k8s.io/api/resource/v1"
+	resourcebetaapi "k8s.io/api/resource/v1beta2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	resourcelisters "k8s.io/client-go/listers/resource/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/dynamic-resource-allocation/structured/schedulerapi"
+	"k8s.io/klog/v2/ktesting"
+)
+
+func TestPreviewDeviceTaintRule(t *testing.T) {
+	claimWithDevice := func(name, device string, tolerations ...resourceapi.DeviceToleration) *resourceapi.ResourceClaim {
+		return &resourceapi.ResourceClaim{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Status: resourceapi.ResourceClaimStatus{
+				Allocation: &resourceapi.AllocationResult{
+					Devices: resourceapi.DeviceAllocationResult{
+						Results: []resourceapi.DeviceRequestAllocationResult{{
+							Request:     "req",
+							Driver:      driver1,
+							Pool:        pool1,
+							Device:      device,
+							Tolerations: tolerations,
+						}},
+					},
+				},
+				ReservedFor: []resourceapi.ResourceClaimConsumerReference{{
+					Resource: "pods",
+					Name:     name + "-pod",
+				}},
+			},
+		}
+	}
+	claimDevice0 := claimWithDevice("claim-0", device0Name)
+	claimDevice1 := claimWithDevice("claim-1", device1Name)
+	claimDevice1Tolerating := claimWithDevice("claim-1-tolerating", device1Name, resourceapi.DeviceToleration{
+		Key:      deviceTaint1.Key,
+		Operator: resourceapi.DeviceTolerationOpExists,
+	})
+	unallocatedClaim := &resourceapi.ResourceClaim{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "unallocated"}}
+	claims := []*resourceapi.ResourceClaim{claimDevice0, claimDevice1, claimDevice1Tolerating, unallocatedClaim}
+
+	noScheduleRule := taintDevice1Rule.DeepCopy()
+	noScheduleRule.Spec.Taint.Effect = resourcebetaapi.DeviceTaintEffectNoSchedule
+
+	testcases := map[string]struct {
+		rule            *resourcebetaapi.DeviceTaintRule
+		withoutLister   bool
+		expectedPreview *DeviceTaintRulePreview
+	}{
+		"all-devices": {
+			rule: taintAllDevicesRule,
+			expectedPreview: &DeviceTaintRulePreview{
+				Devices: []schedulerapi.DeviceID{
+					schedulerapi.MakeDeviceID(driver1, pool1, device0Name),
+					schedulerapi.MakeDeviceID(driver1, pool1, device1Name),
+					schedulerapi.MakeDeviceID(driver1, pool1, device2Name),
+					schedulerapi.MakeDeviceID(driver2, pool2, device2Name),
+				},
+				Claims: []*resourceapi.ResourceClaim{claimDevice0, claimDevice1},
+				Pods: []types.NamespacedName{
+					{Namespace: "default", Name: "claim-0-pod"},
+					{Namespace: "default", Name: "claim-1-pod"},
+				},
+			},
+		},
+		"one-device": {
+			rule: taintDevice1Rule,
+			expectedPreview: &DeviceTaintRulePreview{
+				Devices: []schedulerapi.DeviceID{
+					schedulerapi.MakeDeviceID(driver1, pool1, device1Name),
+				},
+				Claims: []*resourceapi.ResourceClaim{claimDevice1},
+				Pods:   []types.NamespacedName{{Namespace: "default", Name: "claim-1-pod"}},
+			},
+		},
+		"one-pool": {
+			rule: taintPool2DevicesRule,
+			expectedPreview: &DeviceTaintRulePreview{
+				Devices: []schedulerapi.DeviceID{
+					schedulerapi.MakeDeviceID(driver2, pool2, device2Name),
+				},
+			},
+		},
+		"no-schedule": {
+			rule: noScheduleRule,
+			expectedPreview: &DeviceTaintRulePreview{
+				Devices: []schedulerapi.DeviceID{
+					schedulerapi.MakeDeviceID(driver1, pool1, device1Name),
+				},
+			},
+		},
+		"without-lister": {
+			rule:          taintDevice1Rule,
+			withoutLister: true,
+			expectedPreview: &DeviceTaintRulePreview{
+				Devices: []schedulerapi.DeviceID{
+					schedulerapi.MakeDeviceID(driver1, pool1, device1Name),
+				},
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			_, ctx := ktesting.NewTestContext(t)
+			kubeClient := fake.NewSimpleClientset()
+			informerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 10*time.Minute)
+			tracker, err := newTracker(ctx, Options{
+				EnableDeviceTaintRules: true,
+				SliceInformer:          informerFactory.Resource().V1().ResourceSlices(),
+				TaintInformer:          informerFactory.Resource().V1beta2().DeviceTaintRules(),
+				ClassInformer:          informerFactory.Resource().V1().DeviceClasses(),
+			})
+			require.NoError(t, err)
+			defer tracker.Stop()
+			for _, slice := range []*resourceapi.ResourceSlice{sliceWithDevices(slice1, threeDevices), slice2} {
+				require.NoError(t, tracker.resourceSlices.GetStore().Add(slice))
+			}
+
+			var claimLister resourcelisters.ResourceClaimLister
+			if !tc.withoutLister {
+				indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+				for _, claim := range claims {
+					require.NoError(t, indexer.Add(claim))
+				}
+				claimLister = resourcelisters.NewResourceClaimLister(indexer)
+			}
+
+			preview, err := tracker.PreviewDeviceTaintRule(tc.rule, claimLister)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPreview, preview)
+			patchedSlices, err := tracker.ListPatchedResourceSlices()
+			require.NoError(t, err)
+			assert.Empty(t, patchedSlices, "preview must not modify the tracker")
+		})
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"fmt"
+	"reflect"
+
+	resourceapi "k8s.io/api/resource/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/dynamic-resource-allocation/structured/schedulerapi"
+)
+
+// The index names have a prefix because without DeviceTaintRules, the
+// indexers get added to the ResourceSlice informer of the caller.
+const (
+	nodeNameIndexName = "dra-tracker-nodeName"
+	driverIndexName   = "dra-tracker-driver"
+	poolIndexName     = "dra-tracker-pool"
+	deviceIndexName   = "dra-tracker-device"
+)
+
+// queryIndexers are maintained for the ResourceSlices returned by the
+// tracker, i.e. for the patched ResourceSlices if DeviceTaintRules are
+// enabled.
+var queryIndexers = cache.Indexers{
+	nodeNameIndexName: sliceNodeNameIndexFunc,
+	driverIndexName:   sliceDriverIndexFunc,
+	poolIndexName:     slicePoolIndexFunc,
+	deviceIndexName:   sliceDeviceIndexFunc,
+}
+
+// addQueryIndexers adds the query indexers to an informer. Indexers
+// which exist already, for example because another tracker shares
+// the informer, are accepted if they use the same function.
+func addQueryIndexers(informer cache.SharedIndexInformer) error {
+	existingIndexers := informer.GetIndexer().GetIndexers()
+	missingIndexers := cache.Indexers{}
+	for name, indexFunc := range queryIndexers {
+		existingFunc, ok := existingIndexers[name]
+		if !ok {
+			missingIndexers[name] = indexFunc
+			continue
+		}
+		if reflect.ValueOf(existingFunc).Pointer() != reflect.ValueOf(indexFunc).Pointer() {
+			return fmt.Errorf("indexer %q exists already with a different function", name)
+		}
+	}
+	if len(missingIndexers) == 0 {
+		return nil
+	}
+	return informer.AddIndexers(missingIndexers)
+}
+
+func sliceNodeNameIndexFunc(obj any) ([]string, error) {
+	slice := obj.(*resourceapi.ResourceSlice)
+	if slice.Spec.NodeName != nil {
+		return []string{*slice.Spec.NodeName}, nil
+	}
+	var nodeNames []string
+	for _, device := range slice.Spec.Devices {
+		if device.NodeName != nil {
+			nodeNames = append(nodeNames, *device.NodeName)
+		}
+	}
+	return nodeNames, nil
+}
+
+func sliceDriverIndexFunc(obj any) ([]string, error) {
+	slice := obj.(*resourceapi.ResourceSlice)
+	return []string{slice.Spec.Driver}, nil
+}
+
+func slicePoolIndexFunc(obj any) ([]string, error) {
+	slice := obj.(*resourceapi.ResourceSlice)
+	return []string{poolID(slice.Spec.Driver, slice.Spec.Pool.Name)}, nil
+}
+
+func sliceDeviceIndexFunc(obj any) ([]string, error) {
+	slice := obj.(*resourceapi.ResourceSlice)
+	indexValues := make([]string, 0, len(slice.Spec.Devices))
+	for _, device := range slice.Spec.Devices {
+		indexValues = append(indexValues, deviceID(slice.Spec.Driver, slice.Spec.Pool.Name, device.Name))
+	}
+	return indexValues, nil
+}
+
+func poolID(driver, pool string) string {
+	return driver + "/" + pool
+}
+
+// ListPatchedResourceSlicesForNode returns the ResourceSlices which are
+// local to the node, with modifications from DeviceTaints applied. These
+// are the ResourceSlices which have the node name set, either for the
+// entire slice or for some device. ResourceSlices which select nodes with
+// a node selector or are available on all nodes are not included.
+func (t *Tracker) ListPatchedResourceSlicesForNode(nodeName string) ([]*resourceapi.ResourceSlice, error) {
+	return t.listByIndex(nodeNameIndexName, nodeName)
+}
+
+// ListPatchedResourceSlicesForDriver returns the ResourceSlices of one
+// driver, with modifications from DeviceTaints applied.
+func (t *Tracker) ListPatchedResourceSlicesForDriver(driverName string) ([]*resourceapi.ResourceSlice, error) {
+	return t.listByIndex(driverIndexName, driverName)
+}
+
+// ListPatchedResourceSlicesForPool returns the ResourceSlices of one
+// pool, with modifications from DeviceTaints applied. This includes
+// ResourceSlices of older generations of the pool which have not been
+// removed yet.
+func (t *Tracker) ListPatchedResourceSlicesForPool(driverName, poolName string) ([]*resourceapi.ResourceSlice, error) {
+	return t.listByIndex(poolIndexName, poolID(driverName, poolName))
+}
+
+// GetPatchedDevice returns the device with modifications from
+// DeviceTaints applied, nil if not found. If the device is listed in
+// more than one ResourceSlice, then the one from the ResourceSlice
+// with the highest pool generation is returned.
+//
+// The device is shared with the cache and must not be modified.
+func (t *Tracker) GetPatchedDevice(id schedulerapi.DeviceID) (*resourceapi.Device, error) {
+	slices, err := t.listByIndex(deviceIndexName, id.String())
+	if err != nil {
+		return nil, err
+	}
+	var result *resourceapi.Device
+	var generation int64
+	for _, slice := range slices {
+		if result != nil && slice.Spec.Pool.Generation <= generation {
+			continue
+		}
+		for i := range slice.Spec.Devices {
+			if slice.Spec.Devices[i].Name == id.Device.String() {
+				result = &slice.Spec.Devices[i]
+				generation = slice.Spec.Pool.Generation
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func (t *Tracker) listByIndex(indexName, indexedValue string) ([]*resourceapi.ResourceSlice, error) {
+	objs, err := t.sliceIndexer.ByIndex(indexName, indexedValue)
+	if err != nil {
+		return nil, fmt.Errorf("list ResourceSlices by %s index: %w", indexName, err)
+	}
+	return typedSlice[*resourceapi.ResourceSlice](objs), nil
+}
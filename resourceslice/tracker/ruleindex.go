@@ -0,0 +1,365 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	resourcebetaapi "k8s.io/api/resource/v1beta2"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
+)
+
+// ruleIndex tracks which DeviceTaintRules select which devices. Whether
+// a rule selects a device only depends on the driver, pool and device
+// name, so the index is keyed by device ID and shared by all
+// ResourceSlices which list the same device.
+//
+// The index is maintained by the event handlers and is the source
+// of truth for the rules when patching ResourceSlices. Evaluating
+// and updating happen while holding the mutex, so concurrent events
+// for ResourceSlices and DeviceTaintRules cannot overwrite each
+// other's results.
+type ruleIndex struct {
+	mutex sync.Mutex
+
+	// rules contains the most recent version of each rule, by name.
+	rules map[string]*resourcebetaapi.DeviceTaintRule
+	// ruleDevices contains the IDs of the devices selected by each rule.
+	ruleDevices map[string]sets.Set[string]
+	// deviceRules contains the names of the rules which select a device,
+	// by device ID. Devices without rules are not included.
+	deviceRules map[string]sets.Set[string]
+	// driverRules contains the names of all rules, whether they are
+	// in effect or not, by the driver in their device selector.
+	// Rules without a driver are stored under the empty string.
+	driverRules map[string]sets.Set[string]
+	// patchedSlices contains the names of the ResourceSlices which
+	// have devices selected by rules.
+	patchedSlices sets.Set[string]
+	// active contains the names of the rules which are in effect.
+	active sets.Set[string]
+	// timers trigger a re-evaluation of rules with a time span
+	// when they start or stop being in effect.
+	timers map[string]clock.Timer
+}
+
+func newRuleIndex() *ruleIndex {
+	return &ruleIndex{
+		rules:         make(map[string]*resourcebetaapi.DeviceTaintRule),
+		ruleDevices:   make(map[string]sets.Set[string]),
+		deviceRules:   make(map[string]sets.Set[string]),
+		driverRules:   make(map[string]sets.Set[string]),
+		patchedSlices: sets.New[string](),
+		active:        sets.New[string](),
+		timers:        make(map[string]clock.Timer),
+	}
+}
+
+// link records that the rule selects the device.
+func (idx *ruleIndex) link(ruleName, id string) {
+	if idx.ruleDevices[ruleName] == nil {
+		idx.ruleDevices[ruleName] = sets.New[string]()
+	}
+	idx.ruleDevices[ruleName].Insert(id)
+	if idx.deviceRules[id] == nil {
+		idx.deviceRules[id] = sets.New[string]()
+	}
+	idx.deviceRules[id].Insert(ruleName)
+}
+
+// unlink records that the rule does not select the device.
+func (idx *ruleIndex) unlink(ruleName, id string) {
+	if devices := idx.ruleDevices[ruleName]; devices != nil {
+		devices.Delete(id)
+		if devices.Len() == 0 {
+			delete(idx.ruleDevices, ruleName)
+		}
+	}
+	if rules := idx.deviceRules[id]; rules != nil {
+		rules.Delete(ruleName)
+		if rules.Len() == 0 {
+			delete(idx.deviceRules, id)
+		}
+	}
+}
+
+// updateRule stores the new version of the rule, nil if it was
+// removed. Only the devices in ResourceSlices which might be selected
+// by the rule get evaluated. It returns the IDs of the devices which
+// need to be patched again because they were selected before or are
+// selected now and the taint is not the same as before.
+func (t *Tracker) updateRule(ctx context.Context, name string, rule *resourcebetaapi.DeviceTaintRule) sets.Set[string] {
+	idx := t.ruleIndex
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	return t.evaluateRule(ctx, name, rule)
+}
+
+// evaluateRule implements updateRule while holding the mutex.
+// Rules which are not in effect because of their time span
+// select no devices. A timer gets started for the next time
+// when that changes.
+func (t *Tracker) evaluateRule(ctx context.Context, name string, rule *resourcebetaapi.DeviceTaintRule) sets.Set[string] {
+	idx := t.ruleIndex
+	oldRule := idx.rules[name]
+	oldDevices := idx.ruleDevices[name]
+	newDevices := sets.New[string]()
+	var active bool
+	var next time.Time
+	if rule != nil {
+		var err error
+		active, next, err = t.timeSpan.ruleActive(rule, t.clock.Now())
+		if err != nil && rule != oldRule {
+			t.ruleEvent(rule, v1.EventTypeWarning, DeviceTaintRuleReasonInvalidTimeSpan, "DeviceTaintRule is ignored: %v", err)
+		}
+	}
+	if active {
+		for _, sliceName := range t.sliceNamesForPatch(ctx, rule) {
+			obj, exists, err := t.resourceSlices.GetIndexer().GetByKey(sliceName)
+			if err != nil || !exists {
+				continue
+			}
+			slice := obj.(*resourceapi.ResourceSlice)
+			for _, device := range slice.Spec.Devices {
+				if ruleSelects(rule, slice.Spec.Driver, slice.Spec.Pool.Name, device.Name) {
+					newDevices.Insert(deviceID(slice.Spec.Driver, slice.Spec.Pool.Name, device.Name))
+				}
+			}
+		}
+	}
+
+	changedDevices := oldDevices.SymmetricDifference(newDevices)
+	if oldRule != nil && rule != nil && !taintsEqual(t.timeSpan.ruleTaint(oldRule), t.timeSpan.ruleTaint(rule)) {
+		changedDevices = changedDevices.Union(oldDevices.Intersection(newDevices))
+	}
+	for id := range oldDevices.Difference(newDevices) {
+		idx.unlink(name, id)
+	}
+	for id := range newDevices.Difference(oldDevices) {
+		idx.link(name, id)
+	}
+	switch {
+	case rule == nil && oldRule != nil:
+		delete(idx.rules, name)
+		idx.removeDriverRule(ruleDriver(oldRule), name)
+		t.metrics.addDeviceTaintRules(-1)
+	case rule != nil:
+		if oldRule == nil {
+			t.metrics.addDeviceTaintRules(1)
+		} else {
+			t.ruleTransition(rule, idx.active.Has(name), active, newDevices.Len())
+			idx.removeDriverRule(ruleDriver(oldRule), name)
+		}
+		idx.rules[name] = rule
+		idx.addDriverRule(ruleDriver(rule), name)
+	}
+	if active {
+		idx.active.Insert(name)
+	} else {
+		idx.active.Delete(name)
+	}
+
+	if timer := idx.timers[name]; timer != nil {
+		timer.Stop()
+		delete(idx.timers, name)
+	}
+	if !next.IsZero() {
+		idx.timers[name] = t.clock.AfterFunc(next.Sub(t.clock.Now()), func() {
+			// The fake clock invokes callbacks while holding its
+			// lock, so the clock cannot be used directly here.
+			t.wg.Go(func() {
+				t.ruleTimeReached(ctx, name)
+			})
+		})
+	}
+	klog.FromContext(ctx).V(6).Info("DeviceTaintRule evaluated", "deviceTaintRule", klog.KRef("", name), "numDevices", newDevices.Len(), "numChangedDevices", changedDevices.Len())
+	return changedDevices
+}
+
+func (idx *ruleIndex) addDriverRule(driver, ruleName string) {
+	if idx.driverRules[driver] == nil {
+		idx.driverRules[driver] = sets.New[string]()
+	}
+	idx.driverRules[driver].Insert(ruleName)
+}
+
+func (idx *ruleIndex) removeDriverRule(driver, ruleName string) {
+	if rules := idx.driverRules[driver]; rules != nil {
+		rules.Delete(ruleName)
+		if rules.Len() == 0 {
+			delete(idx.driverRules, driver)
+		}
+	}
+}
+
+// ruleDriver returns the driver in the device selector of the rule,
+// the empty string if it selects devices of all drivers.
+func ruleDriver(rule *resourcebetaapi.DeviceTaintRule) string {
+	if rule.Spec.DeviceSelector == nil {
+		return ""
+	}
+	return ptr.Deref(rule.Spec.DeviceSelector.Driver, "")
+}
+
+// rulesSelectingSlice returns the names of the rules which select at
+// least one device in the slice, regardless of whether they are in
+// effect. Only rules for the driver of the slice get checked.
+func (t *Tracker) rulesSelectingSlice(slice *resourceapi.ResourceSlice) []string {
+	idx := t.ruleIndex
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	var names []string
+	for _, driver := range []string{slice.Spec.Driver, ""} {
+		for name := range idx.driverRules[driver] {
+			rule := idx.rules[name]
+			if slices.ContainsFunc(slice.Spec.Devices, func(device resourceapi.Device) bool {
+				return ruleSelects(rule, slice.Spec.Driver, slice.Spec.Pool.Name, device.Name)
+			}) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// updateSliceDevices evaluates all rules for the devices which are
+// listed in the new slice and were not listed in the old one. Either
+// slice may be nil. Devices which are not listed in any ResourceSlice
+// anymore get removed from the index.
+func (t *Tracker) updateSliceDevices(oldSlice, newSlice *resourceapi.ResourceSlice) {
+	idx := t.ruleIndex
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	oldDevices := sliceDeviceIDs(oldSlice)
+	newDevices := sliceDeviceIDs(newSlice)
+	if newSlice != nil {
+		for _, device := range newSlice.Spec.Devices {
+			id := deviceID(newSlice.Spec.Driver, newSlice.Spec.Pool.Name, device.Name)
+			if oldDevices.Has(id) {
+				// Same ID, same result.
+				continue
+			}
+			for name, rule := range idx.rules {
+				if idx.active.Has(name) && ruleSelects(rule, newSlice.Spec.Driver, newSlice.Spec.Pool.Name, device.Name) {
+					idx.link(name, id)
+				} else {
+					idx.unlink(name, id)
+				}
+			}
+		}
+	}
+	for id := range oldDevices.Difference(newDevices) {
+		sliceNames, err := t.resourceSlices.GetIndexer().IndexKeys(driverPoolDeviceIndexName, id)
+		if err != nil || len(sliceNames) > 0 {
+			continue
+		}
+		for name := range idx.deviceRules[id] {
+			idx.unlink(name, id)
+		}
+	}
+}
+
+func sliceDeviceIDs(slice *resourceapi.ResourceSlice) sets.Set[string] {
+	if slice == nil {
+		return nil
+	}
+	ids := sets.New[string]()
+	for _, device := range slice.Spec.Devices {
+		ids.Insert(deviceID(slice.Spec.Driver, slice.Spec.Pool.Name, device.Name))
+	}
+	return ids
+}
+
+// applyPatches returns the slice with the taints of the rules which
+// select its devices. If no rule applies, the slice itself is
+// returned. Otherwise the result is a shallow copy which shares all
+// data with the slice except for the taints of the patched devices.
+func (t *Tracker) applyPatches(ctx context.Context, slice *resourceapi.ResourceSlice) *resourceapi.ResourceSlice {
+	logger := klog.FromContext(ctx)
+	idx := t.ruleIndex
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	patchedSlice := slice
+	for i, device := range slice.Spec.Devices {
+		id := deviceID(slice.Spec.Driver, slice.Spec.Pool.Name, device.Name)
+		ruleNames := idx.deviceRules[id]
+		if ruleNames.Len() == 0 {
+			continue
+		}
+		if patchedSlice == slice {
+			patchedSlice = ptr.To(*slice)
+			patchedSlice.Spec.Devices = slices.Clone(slice.Spec.Devices)
+		}
+		// Clipping ensures that appending allocates a new array
+		// instead of modifying the one of the slice.
+		taints := slices.Clip(device.Taints)
+		for _, name := range sets.List(ruleNames) {
+			logger.V(6).Info("applying matching DeviceTaintRule", "device", id, "deviceTaintRule", klog.KRef("", name))
+			taints = append(taints, t.timeSpan.ruleTaint(idx.rules[name]))
+		}
+		patchedSlice.Spec.Devices[i].Taints = taints
+	}
+	t.setPatched(slice.Name, patchedSlice != slice)
+	return patchedSlice
+}
+
+// setPatched records whether the slice has devices selected by rules.
+// The caller must hold the mutex of the index.
+func (t *Tracker) setPatched(sliceName string, patched bool) {
+	idx := t.ruleIndex
+	switch {
+	case patched && !idx.patchedSlices.Has(sliceName):
+		idx.patchedSlices.Insert(sliceName)
+		t.metrics.addPatchedSlices(1)
+	case !patched && idx.patchedSlices.Has(sliceName):
+		idx.patchedSlices.Delete(sliceName)
+		t.metrics.addPatchedSlices(-1)
+	}
+}
+
+// stopTimers stops all timers for rules with a time span.
+func (t *Tracker) stopTimers() {
+	idx := t.ruleIndex
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	for name, timer := range idx.timers {
+		timer.Stop()
+		delete(idx.timers, name)
+	}
+}
+
+// forgetPatchedSlice gets called after a slice was removed.
+func (t *Tracker) forgetPatchedSlice(sliceName string) {
+	idx := t.ruleIndex
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+
+	t.setPatched(sliceName, false)
+}
@@ -0,0 +1,70 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resourceapi "k8s.io/api/resource/v1"
+	resourcebetaapi "k8s.io/api/resource/v1beta2"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2/ktesting"
+	"k8s.io/utils/ptr"
+)
+
+func TestRuleIndex(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	device := deviceWithName(emptyDevice, device0Name)
+	device.Attributes = map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{"model": {StringValue: ptr.To("a")}}
+	slice := sliceWithDevices(slice1, []resourceapi.Device{device, device1, device2})
+	tracker, err := NewStaticTracker(ctx, StaticObjects{
+		ResourceSlices:   []*resourceapi.ResourceSlice{slice},
+		DeviceTaintRules: []*resourcebetaapi.DeviceTaintRule{taintDevice1Rule},
+	})
+	require.NoError(t, err)
+
+	patchedSlice := tracker.applyPatches(ctx, slice)
+	assert.Equal(t, sliceWithDevices(slice, []resourceapi.Device{device, device1Tainted, device2}), patchedSlice)
+	assert.Equal(t, reflect.ValueOf(slice.Spec.Devices[0].Attributes).UnsafePointer(), reflect.ValueOf(patchedSlice.Spec.Devices[0].Attributes).UnsafePointer(), "unchanged device data should be shared")
+	assert.Nil(t, slice.Spec.Devices[1].Taints, "original slice must not be modified")
+
+	// Only the newly selected device changes.
+	rule := taintNamedDevicesRule(taintAllDevicesRule, device2Name)
+	rule.Name = "rule-2"
+	changedDevices := tracker.updateRule(ctx, rule.Name, rule)
+	assert.Equal(t, sets.New(deviceID(driver1, pool1, device2Name)), changedDevices, "new rule")
+	assert.Empty(t, tracker.updateRule(ctx, rule.Name, rule), "same rule")
+
+	// Changing the taint affects all selected devices.
+	rule = rule.DeepCopy()
+	rule.Spec.Taint.Value = "other"
+	assert.Equal(t, sets.New(deviceID(driver1, pool1, device2Name)), tracker.updateRule(ctx, rule.Name, rule), "new taint")
+
+	assert.Equal(t, sets.New(deviceID(driver1, pool1, device1Name)), tracker.updateRule(ctx, taintDevice1Rule.Name, nil), "removed rule")
+	assert.Equal(t, sets.New(deviceID(driver1, pool1, device2Name)), tracker.updateRule(ctx, rule.Name, nil), "removed rule")
+	assert.Same(t, slice, tracker.applyPatches(ctx, slice), "no rules")
+
+	// Removing the slice removes its devices from the index.
+	tracker.updateRule(ctx, taintAllDevicesRule.Name, taintAllDevicesRule)
+	require.NoError(t, tracker.resourceSlices.GetStore().Delete(slice))
+	tracker.updateSliceDevices(slice, nil)
+	assert.Empty(t, tracker.ruleIndex.deviceRules, "device rules")
+	assert.Empty(t, tracker.ruleIndex.ruleDevices, "rule devices")
+}
@@ -0,0 +1,143 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"context"
+	"fmt"
+
+	resourceapi "k8s.io/api/resource/v1"
+	resourcebetaapi "k8s.io/api/resource/v1beta2"
+	"k8s.io/apimachinery/pkg/runtime"
+	resourcelisters "k8s.io/client-go/listers/resource/v1"
+	resourcebetalisters "k8s.io/client-go/listers/resource/v1beta2"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ObjectStore provides the input for [NewStaticTracker].
+type ObjectStore interface {
+	ListResourceSlices() ([]*resourceapi.ResourceSlice, error)
+	ListDeviceTaintRules() ([]*resourcebetaapi.DeviceTaintRule, error)
+	ListDeviceClasses() ([]*resourceapi.DeviceClass, error)
+}
+
+// StaticObjects is an [ObjectStore] for a fixed set of objects.
+type StaticObjects struct {
+	ResourceSlices   []*resourceapi.ResourceSlice
+	DeviceTaintRules []*resourcebetaapi.DeviceTaintRule
+	DeviceClasses    []*resourceapi.DeviceClass
+}
+
+var _ ObjectStore = StaticObjects{}
+
+func (s StaticObjects) ListResourceSlices() ([]*resourceapi.ResourceSlice, error) {
+	return s.ResourceSlices, nil
+}
+
+func (s StaticObjects) ListDeviceTaintRules() ([]*resourcebetaapi.DeviceTaintRule, error) {
+	return s.DeviceTaintRules, nil
+}
+
+func (s StaticObjects) ListDeviceClasses() ([]*resourceapi.DeviceClass, error) {
+	return s.DeviceClasses, nil
+}
+
+// NewStaticTracker creates a [Tracker] for the objects in the store
+// without informers or a connection to a cluster. This is useful for
+// unit tests and offline tools like simulators. The DeviceTaintRules
+// get applied while creating the tracker, so the patched ResourceSlices
+// are available immediately. The tracker does not pick up later changes
+// in the store, but time-bounded DeviceTaintRules get applied and
+// removed at the right time. Their time span is defined by the default
+// annotations [DefaultDeviceTaintRuleStartAnnotation] and
+// [DefaultDeviceTaintRuleEndAnnotation]. Stop should be called when the tracker
+// is no longer needed to stop the timers for that. Static trackers
+// don't report metrics.
+//
+// The objects are shared with the tracker and must not be modified.
+func NewStaticTracker(ctx context.Context, store ObjectStore) (*Tracker, error) {
+	resourceSlices, err := store.ListResourceSlices()
+	if err != nil {
+		return nil, fmt.Errorf("list ResourceSlices: %w", err)
+	}
+	deviceTaintRules, err := store.ListDeviceTaintRules()
+	if err != nil {
+		return nil, fmt.Errorf("list DeviceTaintRules: %w", err)
+	}
+	deviceClasses, err := store.ListDeviceClasses()
+	if err != nil {
+		return nil, fmt.Errorf("list DeviceClasses: %w", err)
+	}
+
+	sliceInformer := newStaticInformer(&resourceapi.ResourceSlice{}, resourcelisters.NewResourceSliceLister)
+	taintInformer := newStaticInformer(&resourcebetaapi.DeviceTaintRule{}, resourcebetalisters.NewDeviceTaintRuleLister)
+	classInformer := newStaticInformer(&resourceapi.DeviceClass{}, resourcelisters.NewDeviceClassLister)
+	t, err := newTracker(ctx, Options{
+		EnableDeviceTaintRules: true,
+		SliceInformer:          sliceInformer,
+		TaintInformer:          taintInformer,
+		ClassInformer:          classInformer,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// ResourceSlices get added last, so each of them only
+	// needs to be patched once.
+	for _, class := range deviceClasses {
+		if err := t.deviceClasses.GetStore().Add(class); err != nil {
+			return nil, fmt.Errorf("add DeviceClass %s: %w", class.Name, err)
+		}
+	}
+	for _, rule := range deviceTaintRules {
+		if err := t.deviceTaints.GetStore().Add(rule); err != nil {
+			return nil, fmt.Errorf("add DeviceTaintRule %s: %w", rule.Name, err)
+		}
+		t.deviceTaintAdd(ctx)(rule)
+	}
+	for _, slice := range resourceSlices {
+		if err := t.resourceSlices.GetStore().Add(slice); err != nil {
+			return nil, fmt.Errorf("add ResourceSlice %s: %w", slice.Name, err)
+		}
+		t.resourceSliceAdd(ctx)(slice)
+	}
+	close(t.synced)
+	return t, nil
+}
+
+// staticInformer implements the informer interfaces expected in [Options]
+// with an informer which never runs. Its store gets populated directly.
+type staticInformer[L any] struct {
+	informer cache.SharedIndexInformer
+	lister   L
+}
+
+func newStaticInformer[L any](exampleObject runtime.Object, newLister func(cache.Indexer) L) staticInformer[L] {
+	informer := cache.NewSharedIndexInformer(&cache.ListWatch{}, exampleObject, 0, cache.Indexers{})
+	return staticInformer[L]{
+		informer: informer,
+		lister:   newLister(informer.GetIndexer()),
+	}
+}
+
+func (i staticInformer[L]) Informer() cache.SharedIndexInformer {
+	return i.informer
+}
+
+func (i staticInformer[L]) Lister() L {
+	return i.lister
+}
@@ -0,0 +1,77 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	stdcmp "cmp"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resourceapi "k8s.io/api/resource/v1"
+	resourcebetaapi "k8s.io/api/resource/v1beta2"
+	"k8s.io/klog/v2/ktesting"
+)
+
+func TestStaticTracker(t *testing.T) {
+	testcases := map[string]struct {
+		objects               StaticObjects
+		expectedPatchedSlices []*resourceapi.ResourceSlice
+	}{
+		"empty": {
+			expectedPatchedSlices: []*resourceapi.ResourceSlice{},
+		},
+		"no-rules": {
+			objects: StaticObjects{
+				ResourceSlices: []*resourceapi.ResourceSlice{slice1, slice2},
+			},
+			expectedPatchedSlices: []*resourceapi.ResourceSlice{slice1, slice2},
+		},
+		"one-pool": {
+			objects: StaticObjects{
+				ResourceSlices:   []*resourceapi.ResourceSlice{slice1, slice2},
+				DeviceTaintRules: []*resourcebetaapi.DeviceTaintRule{taintPool1DevicesRule},
+				DeviceClasses:    []*resourceapi.DeviceClass{deviceClass1},
+			},
+			expectedPatchedSlices: []*resourceapi.ResourceSlice{slice1Tainted, slice2},
+		},
+		"merged-taints": {
+			objects: StaticObjects{
+				ResourceSlices:   []*resourceapi.ResourceSlice{slice1AlreadyTainted},
+				DeviceTaintRules: []*resourcebetaapi.DeviceTaintRule{taintDevice1Rule},
+			},
+			expectedPatchedSlices: []*resourceapi.ResourceSlice{slice1MergedTaints},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			_, ctx := ktesting.NewTestContext(t)
+			tracker, err := NewStaticTracker(ctx, tc.objects)
+			require.NoError(t, err)
+			assert.True(t, tracker.HasSynced(), "synced")
+
+			patchedSlices, err := tracker.ListPatchedResourceSlices()
+			require.NoError(t, err)
+			slices.SortFunc(patchedSlices, func(s1, s2 *resourceapi.ResourceSlice) int {
+				return stdcmp.Compare(s1.Name, s2.Name)
+			})
+			assert.Equal(t, tc.expectedPatchedSlices, patchedSlices)
+		})
+	}
+}
@@ -0,0 +1,341 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	resourceapi "k8s.io/api/resource/v1"
+	resourcebetaapi "k8s.io/api/resource/v1beta2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DeviceTaintRuleConditionMatched is the type of the condition which
+	// a [StatusWriter] maintains in the status of DeviceTaintRules. It
+	// is true if the rule is in effect and selects at least one device,
+	// false if it selects none or is not in effect because of its time
+	// span, and unknown if the rule could not be evaluated. The message
+	// contains the number of selected devices or the evaluation error.
+	// The last transition time is the time when the status last changed.
+	DeviceTaintRuleConditionMatched = "Matched"
+
+	// DeviceTaintRuleConditionEvaluated is the type of the condition
+	// which records when a [StatusWriter] evaluated the rule. It is
+	// always true. Its observed generation is the generation of the
+	// rule which was evaluated and its last transition time is the time
+	// of that evaluation. It gets updated together with the Matched
+	// condition, i.e. evaluations which don't change the Matched
+	// condition don't cause a status update.
+	DeviceTaintRuleConditionEvaluated = "Evaluated"
+
+	// DeviceTaintRuleReasonDevicesMatched is the reason for a true condition.
+	DeviceTaintRuleReasonDevicesMatched = "DevicesMatched"
+	// DeviceTaintRuleReasonNoDevicesMatched is the reason for a false condition.
+	DeviceTaintRuleReasonNoDevicesMatched = "NoDevicesMatched"
+	// DeviceTaintRuleReasonNotInEffect is the reason for a false condition
+	// of a rule which is outside of its time span.
+	DeviceTaintRuleReasonNotInEffect = "NotInEffect"
+	// DeviceTaintRuleReasonEvaluationFailed is the reason for an unknown condition.
+	DeviceTaintRuleReasonEvaluationFailed = "EvaluationFailed"
+	// DeviceTaintRuleReasonEvaluated is the reason for the Evaluated condition.
+	DeviceTaintRuleReasonEvaluated = "Evaluated"
+
+	// DefaultStatusUpdateInterval is the default for
+	// StatusWriterOptions.UpdateInterval.
+	DefaultStatusUpdateInterval = 10 * time.Second
+)
+
+// StatusWriterOptions configure a [StatusWriter].
+type StatusWriterOptions struct {
+	// KubeClient is used to update the status of DeviceTaintRules.
+	// Required.
+	KubeClient kubernetes.Interface
+
+	// UpdateInterval is the minimum time between two status updates
+	// of the same DeviceTaintRule. Changes during that time get
+	// combined into one update. The default is
+	// [DefaultStatusUpdateInterval].
+	UpdateInterval time.Duration
+
+	// QPS and Burst limit the rate of all status updates. Zero QPS
+	// means no additional limit besides the one of the client.
+	// Burst defaults to one.
+	QPS   float32
+	Burst int
+}
+
+// StatusWriter reports in the status of each DeviceTaintRule whether
+// it matches devices in the ResourceSlices seen by a [Tracker]. It
+// re-evaluates a rule after its spec or the ResourceSlices with
+// devices selected by it change and when it starts or stops being
+// in effect.
+//
+// Only one instance should be active in a cluster, otherwise the
+// instances keep overwriting each other's updates.
+type StatusWriter struct {
+	tracker        *Tracker
+	kubeClient     kubernetes.Interface
+	updateInterval time.Duration
+	limiter        flowcontrol.RateLimiter
+	queue          workqueue.TypedRateLimitingInterface[string]
+	handleError    func(context.Context, error, string, ...any)
+
+	slicesHandle cache.ResourceEventHandlerRegistration
+	taintsHandle cache.ResourceEventHandlerRegistration
+	removeTimer  func()
+
+	wg     sync.WaitGroup
+	cancel func(error)
+}
+
+// StartStatusWriter starts writing the status of DeviceTaintRules
+// based on the informers of the tracker, which must have been
+// started with DeviceTaintRules enabled. The informers must be
+// started separately.
+func StartStatusWriter(ctx context.Context, tracker *Tracker, opts StatusWriterOptions) (finalW *StatusWriter, finalErr error) {
+	if !tracker.enableDeviceTaintRules {
+		return nil, errors.New("DeviceTaintRules are not enabled in the tracker")
+	}
+	if opts.KubeClient == nil {
+		return nil, errors.New("KubeClient is nil")
+	}
+	logger := klog.FromContext(ctx)
+	ctx, cancel := context.WithCancelCause(ctx)
+	w := &StatusWriter{
+		tracker:        tracker,
+		kubeClient:     opts.KubeClient,
+		updateInterval: opts.UpdateInterval,
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(
+			workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "device_taint_rule_status"},
+		),
+		handleError: tracker.handleError,
+		cancel:      cancel,
+	}
+	if w.updateInterval <= 0 {
+		w.updateInterval = DefaultStatusUpdateInterval
+	}
+	if opts.QPS > 0 {
+		w.limiter = flowcontrol.NewTokenBucketRateLimiter(opts.QPS, max(opts.Burst, 1))
+	}
+	defer func() {
+		// If we don't return the writer, stop the partially initialized instance.
+		if finalErr != nil {
+			w.Stop()
+		}
+	}()
+
+	var err error
+	w.taintsHandle, err = tracker.deviceTaints.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			if rule, ok := obj.(*resourcebetaapi.DeviceTaintRule); ok {
+				w.queue.Add(rule.Name)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj any) {
+			oldRule, ok := oldObj.(*resourcebetaapi.DeviceTaintRule)
+			if !ok {
+				return
+			}
+			newRule, ok := newObj.(*resourcebetaapi.DeviceTaintRule)
+			if !ok {
+				return
+			}
+			// Status updates, including our own, don't change the generation.
+			if oldRule.Generation != newRule.Generation {
+				w.queue.AddAfter(newRule.Name, w.updateInterval)
+			}
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("add event handler for DeviceTaintRules: %w", err)
+	}
+	w.slicesHandle, err = tracker.resourceSlices.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			w.sliceChanged(obj)
+		},
+		UpdateFunc: func(oldObj, newObj any) {
+			w.sliceChanged(oldObj)
+			w.sliceChanged(newObj)
+		},
+		DeleteFunc: func(obj any) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			w.sliceChanged(obj)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("add event handler for ResourceSlices: %w", err)
+	}
+	w.removeTimer = tracker.addRuleTimeHandler(func(name string) {
+		w.queue.Add(name)
+	})
+
+	w.wg.Go(func() {
+		defer logger.V(3).Info("DeviceTaintRule status writer stopped")
+		logger.V(3).Info("DeviceTaintRule status writer started")
+		for w.processNextItem(ctx) {
+		}
+	})
+	return w, nil
+}
+
+// Stop ends all background activity and blocks until that shutdown is complete.
+func (w *StatusWriter) Stop() {
+	if w == nil {
+		return
+	}
+	w.cancel(errors.New("DeviceTaintRule status writer was asked to stop"))
+	w.queue.ShutDown()
+	if w.taintsHandle != nil {
+		_ = w.tracker.deviceTaints.RemoveEventHandler(w.taintsHandle)
+	}
+	if w.slicesHandle != nil {
+		_ = w.tracker.resourceSlices.RemoveEventHandler(w.slicesHandle)
+	}
+	if w.removeTimer != nil {
+		w.removeTimer()
+	}
+	w.wg.Wait()
+}
+
+// sliceChanged queues all rules which select a device in the slice.
+func (w *StatusWriter) sliceChanged(obj any) {
+	slice, ok := obj.(*resourceapi.ResourceSlice)
+	if !ok {
+		return
+	}
+	for _, name := range w.tracker.rulesSelectingSlice(slice) {
+		w.queue.AddAfter(name, w.updateInterval)
+	}
+}
+
+func (w *StatusWriter) processNextItem(ctx context.Context) bool {
+	name, shutdown := w.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer w.queue.Done(name)
+
+	if err := w.syncRule(ctx, name); err != nil {
+		w.handleError(ctx, err, "updating DeviceTaintRule status", "rule", name)
+		w.queue.AddRateLimited(name)
+		return true
+	}
+	w.queue.Forget(name)
+	return true
+}
+
+// syncRule evaluates the rule and writes the result into its status
+// if it changed.
+func (w *StatusWriter) syncRule(ctx context.Context, name string) error {
+	obj, exists, err := w.tracker.deviceTaints.GetStore().GetByKey(name)
+	if err != nil {
+		return fmt.Errorf("get DeviceTaintRule: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+	rule := obj.(*resourcebetaapi.DeviceTaintRule)
+	now := metav1.NewTime(w.tracker.clock.Now())
+	conditions := slices.Clone(rule.Status.Conditions)
+	if !meta.SetStatusCondition(&conditions, w.evaluate(rule, now.Time)) &&
+		meta.FindStatusCondition(conditions, DeviceTaintRuleConditionEvaluated) != nil {
+		klog.FromContext(ctx).V(5).Info("DeviceTaintRule status is up-to-date", "rule", klog.KObj(rule))
+		return nil
+	}
+	// The Evaluated condition never transitions, so it must be
+	// replaced to record the time of this evaluation.
+	meta.RemoveStatusCondition(&conditions, DeviceTaintRuleConditionEvaluated)
+	meta.SetStatusCondition(&conditions, metav1.Condition{
+		Type:               DeviceTaintRuleConditionEvaluated,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: rule.Generation,
+		LastTransitionTime: now,
+		Reason:             DeviceTaintRuleReasonEvaluated,
+	})
+	rule = rule.DeepCopy()
+	rule.Status.Conditions = conditions
+
+	if w.limiter != nil {
+		if err := w.limiter.Wait(ctx); err != nil {
+			return fmt.Errorf("wait for rate limiter: %w", err)
+		}
+	}
+	_, err = w.kubeClient.ResourceV1beta2().DeviceTaintRules().UpdateStatus(ctx, rule, metav1.UpdateOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		// A conflict is handled by trying again with the
+		// updated rule from the informer cache.
+		return fmt.Errorf("update DeviceTaintRule status: %w", err)
+	}
+	klog.FromContext(ctx).V(5).Info("Updated DeviceTaintRule status", "rule", klog.KObj(rule))
+	return nil
+}
+
+// evaluate returns the Matched condition for the rule at the given time.
+// Whether the rule is in effect is determined the same way as in the
+// tracker. The tracker requeues the rule when that changes.
+func (w *StatusWriter) evaluate(rule *resourcebetaapi.DeviceTaintRule, now time.Time) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               DeviceTaintRuleConditionMatched,
+		ObservedGeneration: rule.Generation,
+	}
+	active, _, err := w.tracker.timeSpan.ruleActive(rule, now)
+	if err != nil {
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = DeviceTaintRuleReasonEvaluationFailed
+		condition.Message = err.Error()
+		return condition
+	}
+	devices, err := w.tracker.matchingDevices(rule)
+	switch {
+	case err != nil:
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = DeviceTaintRuleReasonEvaluationFailed
+		condition.Message = err.Error()
+	case !active:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = DeviceTaintRuleReasonNotInEffect
+		condition.Message = fmt.Sprintf("not in effect, %d devices matched", devices.Len())
+	case devices.Len() == 0:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = DeviceTaintRuleReasonNoDevicesMatched
+		condition.Message = "0 devices matched"
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = DeviceTaintRuleReasonDevicesMatched
+		condition.Message = fmt.Sprintf("%d devices matched", devices.Len())
+	}
+	return condition
+}
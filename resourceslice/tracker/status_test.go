@@ -0,0 +1,130 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resourceapi "k8s.io/api/resource/v1"
+	resourcebetaapi "k8s.io/api/resource/v1beta2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/klog/v2/ktesting"
+)
+
+func TestStatusWriter(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	unknownDeviceRule := taintNamedDevicesRule(taintAllDevicesRule, "no-such-device")
+	unknownDeviceRule.Name = "unknown-device"
+	expiredRule := taintDevice1Rule.DeepCopy()
+	expiredRule.Name = "expired"
+	expiredRule.Annotations = map[string]string{DefaultDeviceTaintRuleEndAnnotation: time.Now().Add(-time.Hour).Format(time.RFC3339)}
+	// Long enough to observe the rule while it is in effect.
+	expiringRule := taintDevice1Rule.DeepCopy()
+	expiringRule.Name = "expiring"
+	expiringRule.Annotations = map[string]string{DefaultDeviceTaintRuleEndAnnotation: time.Now().Add(3 * time.Second).Format(time.RFC3339)}
+	kubeClient := fake.NewSimpleClientset(
+		sliceWithDevices(slice1, threeDevices),
+		slice2,
+		taintDevice1Rule,
+		unknownDeviceRule,
+		expiredRule,
+		expiringRule,
+	)
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 10*time.Minute)
+	tracker, err := StartTracker(ctx, Options{
+		EnableDeviceTaintRules: true,
+		SliceInformer:          informerFactory.Resource().V1().ResourceSlices(),
+		TaintInformer:          informerFactory.Resource().V1beta2().DeviceTaintRules(),
+		ClassInformer:          informerFactory.Resource().V1().DeviceClasses(),
+		KubeClient:             kubeClient,
+	})
+	require.NoError(t, err)
+	defer tracker.Stop()
+	writer, err := StartStatusWriter(ctx, tracker, StatusWriterOptions{
+		KubeClient:     kubeClient,
+		UpdateInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer writer.Stop()
+	informerFactory.Start(ctx.Done())
+	defer informerFactory.Shutdown()
+
+	expectCondition := func(ruleName string, status metav1.ConditionStatus, reason, message string) {
+		t.Helper()
+		assert.EventuallyWithT(t, func(t *assert.CollectT) {
+			rule, err := kubeClient.ResourceV1beta2().DeviceTaintRules().Get(ctx, ruleName, metav1.GetOptions{})
+			require.NoError(t, err)
+			condition := meta.FindStatusCondition(rule.Status.Conditions, DeviceTaintRuleConditionMatched)
+			require.NotNil(t, condition, "condition")
+			assert.Equal(t, status, condition.Status, "status")
+			assert.Equal(t, reason, condition.Reason, "reason")
+			assert.Equal(t, message, condition.Message, "message")
+			assert.False(t, condition.LastTransitionTime.IsZero(), "last transition time")
+			evaluated := meta.FindStatusCondition(rule.Status.Conditions, DeviceTaintRuleConditionEvaluated)
+			require.NotNil(t, evaluated, "evaluated condition")
+			assert.Equal(t, rule.Generation, evaluated.ObservedGeneration, "evaluated generation")
+			assert.False(t, evaluated.LastTransitionTime.Before(&condition.LastTransitionTime), "evaluated before last transition")
+		}, 10*time.Second, 10*time.Millisecond)
+	}
+	expectCondition(taintDevice1Rule.Name, metav1.ConditionTrue, DeviceTaintRuleReasonDevicesMatched, "1 devices matched")
+	expectCondition(unknownDeviceRule.Name, metav1.ConditionFalse, DeviceTaintRuleReasonNoDevicesMatched, "0 devices matched")
+	expectCondition(expiredRule.Name, metav1.ConditionFalse, DeviceTaintRuleReasonNotInEffect, "not in effect, 1 devices matched")
+	expectCondition(expiringRule.Name, metav1.ConditionTrue, DeviceTaintRuleReasonDevicesMatched, "1 devices matched")
+
+	// The tracker requeues the rule when it stops being in effect.
+	expectCondition(expiringRule.Name, metav1.ConditionFalse, DeviceTaintRuleReasonNotInEffect, "not in effect, 1 devices matched")
+
+	// Evaluating again without changes doesn't write the status.
+	numStatusUpdates := func() int {
+		var num int
+		for _, action := range kubeClient.Actions() {
+			if action.GetVerb() == "update" && action.GetSubresource() == "status" {
+				num++
+			}
+		}
+		return num
+	}
+	assert.EventuallyWithT(t, func(t *assert.CollectT) {
+		num := numStatusUpdates()
+		assert.NoError(t, writer.syncRule(ctx, unknownDeviceRule.Name))
+		assert.Equal(t, num, numStatusUpdates(), "number of status updates")
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// Adding the device triggers a re-evaluation.
+	slice := sliceWithDevices(slice2, []resourceapi.Device{device2, deviceWithName(emptyDevice, "no-such-device")})
+	_, err = kubeClient.ResourceV1().ResourceSlices().Update(ctx, slice, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	expectCondition(unknownDeviceRule.Name, metav1.ConditionTrue, DeviceTaintRuleReasonDevicesMatched, "1 devices matched")
+	// Only the relevant rules get queued.
+	assert.ElementsMatch(t, []string{unknownDeviceRule.Name}, tracker.rulesSelectingSlice(slice))
+
+	// So does changing the rule.
+	rule, err := kubeClient.ResourceV1beta2().DeviceTaintRules().Get(ctx, taintDevice1Rule.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	rule = rule.DeepCopy()
+	rule.Generation++
+	rule.Spec.DeviceSelector = &resourcebetaapi.DeviceTaintSelector{Driver: &driver1}
+	_, err = kubeClient.ResourceV1beta2().DeviceTaintRules().Update(ctx, rule, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	expectCondition(taintDevice1Rule.Name, metav1.ConditionTrue, DeviceTaintRuleReasonDevicesMatched, "3 devices matched")
+}
@@ -0,0 +1,187 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	resourcebetaapi "k8s.io/api/resource/v1beta2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DefaultDeviceTaintRuleStartAnnotation is the default for
+	// Options.DeviceTaintRuleStartAnnotation. This annotation can be
+	// set on a DeviceTaintRule to apply its taint only at or after the
+	// given time, for example at the beginning of a maintenance window.
+	// The value must be in RFC 3339 format.
+	DefaultDeviceTaintRuleStartAnnotation = "dynamic-resource-allocation.k8s.io/device-taint-rule-start"
+
+	// DefaultDeviceTaintRuleEndAnnotation is the default for
+	// Options.DeviceTaintRuleEndAnnotation. This annotation can be
+	// set on a DeviceTaintRule to remove its taint again at the given
+	// time, for example at the end of a maintenance window. The value
+	// must be in RFC 3339 format and after the start time, if there
+	// is one.
+	DefaultDeviceTaintRuleEndAnnotation = "dynamic-resource-allocation.k8s.io/device-taint-rule-end"
+)
+
+// Reasons of the Events emitted for time-bounded DeviceTaintRules.
+const (
+	DeviceTaintRuleReasonActivated       = "Activated"
+	DeviceTaintRuleReasonDeactivated     = "Deactivated"
+	DeviceTaintRuleReasonInvalidTimeSpan = "InvalidTimeSpan"
+)
+
+// timeSpanAnnotations are the keys of the annotations which
+// define the time span of a DeviceTaintRule.
+type timeSpanAnnotations struct {
+	start, end string
+}
+
+func newTimeSpanAnnotations(opts Options) timeSpanAnnotations {
+	a := timeSpanAnnotations{
+		start: opts.DeviceTaintRuleStartAnnotation,
+		end:   opts.DeviceTaintRuleEndAnnotation,
+	}
+	if a.start == "" {
+		a.start = DefaultDeviceTaintRuleStartAnnotation
+	}
+	if a.end == "" {
+		a.end = DefaultDeviceTaintRuleEndAnnotation
+	}
+	return a
+}
+
+// ruleTimeSpan returns the start and end time of the rule.
+// Zero times mean that there is no bound.
+func (a timeSpanAnnotations) ruleTimeSpan(rule *resourcebetaapi.DeviceTaintRule) (start, end time.Time, err error) {
+	if value, ok := rule.Annotations[a.start]; ok {
+		start, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("annotation %s: %w", a.start, err)
+		}
+	}
+	if value, ok := rule.Annotations[a.end]; ok {
+		end, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("annotation %s: %w", a.end, err)
+		}
+		if !start.IsZero() && !end.After(start) {
+			return time.Time{}, time.Time{}, fmt.Errorf("annotation %s: %s is not after the start time %s", a.end, value, start.Format(time.RFC3339))
+		}
+	}
+	return start, end, nil
+}
+
+// ruleActive returns true if the rule is in effect at the given
+// time. If that changes later, the time of that change is returned
+// as next transition. A rule with an invalid time span is not in
+// effect because applying a taint at the wrong time might evict
+// pods unexpectedly.
+func (a timeSpanAnnotations) ruleActive(rule *resourcebetaapi.DeviceTaintRule, now time.Time) (active bool, next time.Time, err error) {
+	start, end, err := a.ruleTimeSpan(rule)
+	switch {
+	case err != nil:
+		return false, time.Time{}, err
+	case !start.IsZero() && now.Before(start):
+		return false, start, nil
+	case !end.IsZero() && !now.Before(end):
+		return false, time.Time{}, nil
+	default:
+		return true, end, nil
+	}
+}
+
+// ruleTaint returns the taint of the rule as it gets added to devices.
+// If the rule has a start time, that time is used as the time when
+// the taint was added because that is when it takes effect.
+func (a timeSpanAnnotations) ruleTaint(rule *resourcebetaapi.DeviceTaintRule) resourceapi.DeviceTaint {
+	taint := taintFromRule(rule)
+	start, _, err := a.ruleTimeSpan(rule)
+	if err == nil && !start.IsZero() && (taint.TimeAdded == nil || taint.TimeAdded.Time.Before(start)) {
+		taint.TimeAdded = &metav1.Time{Time: start}
+	}
+	return taint
+}
+
+// ruleTimeReached gets called by a timer when a time-bounded rule
+// starts or stops being in effect.
+func (t *Tracker) ruleTimeReached(ctx context.Context, name string) {
+	if ctx.Err() != nil {
+		// Stopped in the meantime.
+		return
+	}
+	idx := t.ruleIndex
+	idx.mutex.Lock()
+	rule := idx.rules[name]
+	if rule == nil {
+		// Removed in the meantime.
+		idx.mutex.Unlock()
+		return
+	}
+	changedDevices := t.evaluateRule(ctx, name, rule)
+	idx.mutex.Unlock()
+
+	t.syncDevices(ctx, changedDevices)
+
+	t.ruleTimeMutex.Lock()
+	defer t.ruleTimeMutex.Unlock()
+	for handler := range t.ruleTimeHandlers {
+		(*handler)(name)
+	}
+}
+
+// addRuleTimeHandler registers a function which gets called by
+// ruleTimeReached. The returned function removes it again.
+func (t *Tracker) addRuleTimeHandler(handler func(name string)) (remove func()) {
+	t.ruleTimeMutex.Lock()
+	defer t.ruleTimeMutex.Unlock()
+	if t.ruleTimeHandlers == nil {
+		t.ruleTimeHandlers = make(map[*func(name string)]struct{})
+	}
+	key := &handler
+	t.ruleTimeHandlers[key] = struct{}{}
+	return func() {
+		t.ruleTimeMutex.Lock()
+		defer t.ruleTimeMutex.Unlock()
+		delete(t.ruleTimeHandlers, key)
+	}
+}
+
+// ruleEvent emits an Event for the rule, if possible.
+func (t *Tracker) ruleEvent(rule *resourcebetaapi.DeviceTaintRule, eventType, reason, messageFmt string, args ...any) {
+	if t.recorder == nil {
+		return
+	}
+	t.recorder.Eventf(rule, eventType, reason, messageFmt, args...)
+}
+
+// ruleTransition emits an Event if a rule which was known before
+// starts or stops being in effect.
+func (t *Tracker) ruleTransition(rule *resourcebetaapi.DeviceTaintRule, wasActive, isActive bool, numDevices int) {
+	switch {
+	case !wasActive && isActive:
+		t.ruleEvent(rule, v1.EventTypeNormal, DeviceTaintRuleReasonActivated, "DeviceTaintRule is in effect, tainting %d devices", numDevices)
+	case wasActive && !isActive:
+		t.ruleEvent(rule, v1.EventTypeNormal, DeviceTaintRuleReasonDeactivated, "DeviceTaintRule is not in effect anymore")
+	}
+}
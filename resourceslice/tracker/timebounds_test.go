@@ -0,0 +1,192 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1"
+	resourcebetaapi "k8s.io/api/resource/v1beta2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/klog/v2/ktesting"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+func TestTimeBoundedRules(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	kubeClient := fake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 10*time.Minute)
+	tracker, err := newTracker(ctx, Options{
+		EnableDeviceTaintRules: true,
+		SliceInformer:          informerFactory.Resource().V1().ResourceSlices(),
+		TaintInformer:          informerFactory.Resource().V1beta2().DeviceTaintRules(),
+		ClassInformer:          informerFactory.Resource().V1().DeviceClasses(),
+		KubeClient:             kubeClient,
+	})
+	require.NoError(t, err)
+	defer tracker.Stop()
+	fakeClock := testingclock.NewFakeClock(now)
+	tracker.clock = fakeClock
+	tCtx := &testContext{T: t, Context: ctx, Tracker: tracker, Clientset: kubeClient}
+
+	start := now.Add(time.Hour)
+	end := now.Add(2 * time.Hour)
+	rule := taintDevice1Rule.DeepCopy()
+	rule.Annotations = map[string]string{
+		DefaultDeviceTaintRuleStartAnnotation: start.Format(time.RFC3339),
+		DefaultDeviceTaintRuleEndAnnotation:   end.Format(time.RFC3339),
+	}
+	invalidRule := taintDevice1Rule.DeepCopy()
+	invalidRule.Name = "invalid"
+	invalidRule.Annotations = map[string]string{
+		DefaultDeviceTaintRuleStartAnnotation: end.Format(time.RFC3339),
+		DefaultDeviceTaintRuleEndAnnotation:   start.Format(time.RFC3339),
+	}
+	applyEventPair(tCtx, add(slice1))
+	applyEventPair(tCtx, add(rule))
+	applyEventPair(tCtx, add(invalidRule))
+
+	expectSlice := func(expected *resourceapi.ResourceSlice) {
+		t.Helper()
+		assert.EventuallyWithT(t, func(t *assert.CollectT) {
+			patchedSlices, err := tracker.ListPatchedResourceSlices()
+			require.NoError(t, err)
+			assert.Equal(t, []*resourceapi.ResourceSlice{expected}, patchedSlices)
+		}, 10*time.Second, time.Millisecond)
+	}
+	expectEvent := func(ruleName, eventType, reason string) {
+		t.Helper()
+		assert.EventuallyWithT(t, func(t *assert.CollectT) {
+			events, err := kubeClient.CoreV1().Events("").List(ctx, metav1.ListOptions{})
+			require.NoError(t, err)
+			found := false
+			for _, event := range events.Items {
+				if event.InvolvedObject.Name == ruleName && event.Type == eventType && event.Reason == reason {
+					found = true
+				}
+			}
+			assert.True(t, found, "%s event %s for %s", eventType, reason, ruleName)
+		}, 10*time.Second, 10*time.Millisecond)
+	}
+
+	expectSlice(slice1)
+	expectEvent(invalidRule.Name, v1.EventTypeWarning, DeviceTaintRuleReasonInvalidTimeSpan)
+
+	// The taint gets added at the start time, with that as time
+	// when it was added.
+	fakeClock.SetTime(start)
+	taint := deviceTaint1
+	taint.TimeAdded = &metav1.Time{Time: start}
+	expectSlice(sliceWithDevices(slice1, []resourceapi.Device{deviceWithTaints(device1, []resourceapi.DeviceTaint{taint})}))
+	expectEvent(rule.Name, v1.EventTypeNormal, DeviceTaintRuleReasonActivated)
+
+	// It gets removed at the end time.
+	fakeClock.SetTime(end)
+	expectSlice(slice1)
+	expectEvent(rule.Name, v1.EventTypeNormal, DeviceTaintRuleReasonDeactivated)
+
+	// Without time span, the rule applies immediately.
+	applyEventPair(tCtx, update(rule, taintDevice1Rule))
+	expectSlice(slice1Tainted)
+}
+
+func TestRuleActive(t *testing.T) {
+	start := now.Add(time.Hour)
+	end := now.Add(2 * time.Hour)
+	ruleWithAnnotations := func(annotations map[string]string) *resourcebetaapi.DeviceTaintRule {
+		return &resourcebetaapi.DeviceTaintRule{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+	}
+
+	testcases := map[string]struct {
+		opts           Options
+		rule           *resourcebetaapi.DeviceTaintRule
+		now            time.Time
+		expectedActive bool
+		expectedNext   time.Time
+		expectedErr    string
+	}{
+		"no-time-span": {
+			rule:           ruleWithAnnotations(nil),
+			now:            now,
+			expectedActive: true,
+		},
+		"before-start": {
+			rule:         ruleWithAnnotations(map[string]string{DefaultDeviceTaintRuleStartAnnotation: start.Format(time.RFC3339)}),
+			now:          now,
+			expectedNext: start,
+		},
+		"at-start": {
+			rule:           ruleWithAnnotations(map[string]string{DefaultDeviceTaintRuleStartAnnotation: start.Format(time.RFC3339)}),
+			now:            start,
+			expectedActive: true,
+		},
+		"before-end": {
+			rule:           ruleWithAnnotations(map[string]string{DefaultDeviceTaintRuleEndAnnotation: end.Format(time.RFC3339)}),
+			now:            now,
+			expectedActive: true,
+			expectedNext:   end,
+		},
+		"at-end": {
+			rule: ruleWithAnnotations(map[string]string{DefaultDeviceTaintRuleEndAnnotation: end.Format(time.RFC3339)}),
+			now:  end,
+		},
+		"custom-annotation": {
+			opts:         Options{DeviceTaintRuleStartAnnotation: "example.com/start"},
+			rule:         ruleWithAnnotations(map[string]string{"example.com/start": start.Format(time.RFC3339)}),
+			now:          now,
+			expectedNext: start,
+		},
+		"default-annotation-ignored": {
+			opts:           Options{DeviceTaintRuleStartAnnotation: "example.com/start"},
+			rule:           ruleWithAnnotations(map[string]string{DefaultDeviceTaintRuleStartAnnotation: start.Format(time.RFC3339)}),
+			now:            now,
+			expectedActive: true,
+		},
+		"invalid-start": {
+			rule:        ruleWithAnnotations(map[string]string{DefaultDeviceTaintRuleStartAnnotation: "tomorrow"}),
+			now:         now,
+			expectedErr: `annotation dynamic-resource-allocation.k8s.io/device-taint-rule-start: parsing time "tomorrow"`,
+		},
+		"end-before-start": {
+			rule: ruleWithAnnotations(map[string]string{
+				DefaultDeviceTaintRuleStartAnnotation: end.Format(time.RFC3339),
+				DefaultDeviceTaintRuleEndAnnotation:   start.Format(time.RFC3339),
+			}),
+			now:         now,
+			expectedErr: "is not after the start time",
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			active, next, err := newTimeSpanAnnotations(tc.opts).ruleActive(tc.rule, tc.now)
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedActive, active, "active")
+			assert.True(t, tc.expectedNext.Equal(next), "expected next transition %s, got %s", tc.expectedNext, next)
+		})
+	}
+}
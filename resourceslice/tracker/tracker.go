@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"slices"
 	"sync"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
@@ -40,6 +41,7 @@ import (
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/buffer"
+	"k8s.io/utils/clock"
 	"k8s.io/utils/ptr"
 )
 
@@ -54,6 +56,10 @@ const (
 // Tracker maintains a view of ResourceSlice objects with matching
 // DeviceTaintRules applied. It is backed by informers to process
 // potential changes to resolved ResourceSlices asynchronously.
+//
+// DeviceTaintRules with a time span set through the annotations
+// configured in [Options] only get applied during that time span. The tracker emits an Event
+// for the rule when it starts or stops being in effect.
 type Tracker struct {
 	enableDeviceTaintRules bool
 
@@ -64,13 +70,41 @@ type Tracker struct {
 	deviceTaintsHandle    cache.ResourceEventHandlerRegistration
 	deviceClasses         cache.SharedIndexInformer
 	deviceClassesHandle   cache.ResourceEventHandlerRegistration
-	patchedResourceSlices cache.Store
+	patchedResourceSlices cache.Indexer
 	broadcaster           record.EventBroadcaster
 	recorder              record.EventRecorder
 	// handleError usually refers to [utilruntime.HandleErrorWithContext] but
 	// may be overridden in tests.
 	handleError func(context.Context, error, string, ...any)
 
+	// logger is used for warnings about slow event handlers,
+	// which get invoked without a context.
+	logger               klog.Logger
+	slowHandlerThreshold time.Duration
+
+	// metrics is nil for trackers which don't report metrics.
+	metrics *trackerMetrics
+
+	// clock determines when time-bounded DeviceTaintRules are in effect.
+	clock clock.WithDelayedExecution
+
+	// timeSpan defines how the time span of DeviceTaintRules is set.
+	timeSpan timeSpanAnnotations
+
+	// ruleIndex tracks which DeviceTaintRules select which devices.
+	ruleIndex *ruleIndex
+
+	// ruleTimeHandlers get called with the name of a time-bounded
+	// DeviceTaintRule after it started or stopped being in effect.
+	// Protected by ruleTimeMutex.
+	ruleTimeMutex    sync.Mutex
+	ruleTimeHandlers map[*func(name string)]struct{}
+
+	// sliceIndexer provides the indices for queries. It is
+	// patchedResourceSlices if DeviceTaintRules are enabled,
+	// otherwise the indexer of the ResourceSlice informer.
+	sliceIndexer cache.Indexer
+
 	// wg and cancel track resp. kill goroutines.
 	wg     sync.WaitGroup
 	cancel func(error)
@@ -125,15 +159,39 @@ type Options struct {
 	// KubeClient is used to generate Events when CEL expressions
 	// encounter runtime errors.
 	KubeClient kubernetes.Interface
+
+	// SlowHandlerThreshold enables logging a warning when delivering
+	// one event to an event handler added with [Tracker.AddEventHandler]
+	// takes longer than this. Events are delivered sequentially, so a
+	// slow handler delays all other handlers. Zero disables the warning.
+	SlowHandlerThreshold time.Duration
+
+	// DeviceTaintRuleStartAnnotation and DeviceTaintRuleEndAnnotation
+	// are the keys of the annotations which limit when a DeviceTaintRule
+	// is in effect. The defaults are [DefaultDeviceTaintRuleStartAnnotation]
+	// and [DefaultDeviceTaintRuleEndAnnotation].
+	DeviceTaintRuleStartAnnotation string
+	DeviceTaintRuleEndAnnotation   string
+
+	// Name is used as value of the tracker label in metrics.
+	// Trackers in the same process should have different names,
+	// otherwise they update the same metrics. The default
+	// is [DefaultName].
+	Name string
 }
 
 // StartTracker creates and initializes informers for a new [Tracker].
 func StartTracker(ctx context.Context, opts Options) (finalT *Tracker, finalErr error) {
 	if !opts.EnableDeviceTaintRules {
 		// Minimal wrapper. All public methods shortcut by calling the underlying informer.
+		informer := opts.SliceInformer.Informer()
+		if err := addQueryIndexers(informer); err != nil {
+			return nil, fmt.Errorf("failed to add query indices to ResourceSlice informer: %w", err)
+		}
 		return &Tracker{
 			resourceSliceLister: opts.SliceInformer.Lister(),
-			resourceSlices:      opts.SliceInformer.Informer(),
+			resourceSlices:      informer,
+			sliceIndexer:        informer.GetIndexer(),
 		}, nil
 	}
 
@@ -141,6 +199,7 @@ func StartTracker(ctx context.Context, opts Options) (finalT *Tracker, finalErr
 	if err != nil {
 		return nil, err
 	}
+	t.metrics = newTrackerMetrics(opts.Name)
 	defer func() {
 		// If we don't return the tracker, stop the partially initialized instance.
 		if finalErr != nil {
@@ -161,12 +220,18 @@ func newTracker(ctx context.Context, opts Options) (finalT *Tracker, finalErr er
 		resourceSlices:         opts.SliceInformer.Informer(),
 		deviceTaints:           opts.TaintInformer.Informer(),
 		deviceClasses:          opts.ClassInformer.Informer(),
-		patchedResourceSlices:  cache.NewStore(cache.MetaNamespaceKeyFunc),
+		patchedResourceSlices:  cache.NewIndexer(cache.MetaNamespaceKeyFunc, queryIndexers),
+		ruleIndex:              newRuleIndex(),
+		logger:                 klog.FromContext(ctx),
+		slowHandlerThreshold:   opts.SlowHandlerThreshold,
+		clock:                  clock.RealClock{},
+		timeSpan:               newTimeSpanAnnotations(opts),
 		handleError:            utilruntime.HandleErrorWithContext,
 		synced:                 make(chan struct{}),
 		cancel:                 func(error) {}, // Real function set in initInformers.
 		eventQueue:             *buffer.NewRing[func()](buffer.RingOptions{InitialSize: 0, NormalSize: 4}),
 	}
+	t.sliceIndexer = t.patchedResourceSlices
 	defer func() {
 		// If we don't return the tracker, stop the partially initialized instance.
 		if finalErr != nil {
@@ -277,6 +342,7 @@ func (t *Tracker) Stop() {
 	}
 
 	t.cancel(errors.New("stopped"))
+	t.stopTimers()
 
 	if t.broadcaster != nil {
 		t.broadcaster.Shutdown()
@@ -286,6 +352,7 @@ func (t *Tracker) Stop() {
 	_ = t.deviceClasses.RemoveEventHandler(t.deviceClassesHandle)
 
 	t.wg.Wait()
+	t.metrics.delete()
 }
 
 // ListPatchedResourceSlices returns all ResourceSlices in the cluster with
@@ -318,9 +385,10 @@ func (t *Tracker) AddEventHandler(handler cache.ResourceEventHandler) (cache.Res
 	t.eventHandlers = append(t.eventHandlers, handler)
 	allObjs, _ := t.ListPatchedResourceSlices()
 	for _, obj := range allObjs {
-		t.eventQueue.WriteOne(func() {
+		t.eventQueue.WriteOne(t.deliverEvent("add", func() {
 			handler.OnAdd(obj, true)
-		})
+		}))
+		t.metrics.addQueuedEvents(1)
 	}
 
 	// The tracker itself provides HasSynced for all registered event handlers.
@@ -340,6 +408,7 @@ func (t *Tracker) emitEvents() {
 		if !ok {
 			return
 		}
+		t.metrics.addQueuedEvents(-1)
 		func() {
 			defer utilruntime.HandleCrash()
 			deliver()
@@ -358,18 +427,43 @@ func (t *Tracker) pushEvent(oldObj, newObj any) {
 	defer t.rwMutex.Unlock()
 	for _, handler := range t.eventHandlers {
 		if oldObj == nil {
-			t.eventQueue.WriteOne(func() {
+			t.eventQueue.WriteOne(t.deliverEvent("add", func() {
 				handler.OnAdd(newObj, false)
-			})
+			}))
 		} else if newObj == nil {
-			t.eventQueue.WriteOne(func() {
+			t.eventQueue.WriteOne(t.deliverEvent("delete", func() {
 				handler.OnDelete(oldObj)
-			})
+			}))
 		} else {
-			t.eventQueue.WriteOne(func() {
+			t.eventQueue.WriteOne(t.deliverEvent("update", func() {
 				handler.OnUpdate(oldObj, newObj)
+			}))
+		}
+		t.metrics.addQueuedEvents(1)
+	}
+}
+
+// deliverEvent wraps the delivery of one event to one event handler
+// such that the duration gets measured and, if enabled, an error
+// gets logged as soon as it takes too long. A handler which never
+// returns therefore still gets reported.
+func (t *Tracker) deliverEvent(eventType string, deliver func()) func() {
+	return func() {
+		start := time.Now()
+		// Also record the duration of handlers which panic.
+		defer func() {
+			t.metrics.observeHandler(eventType, time.Since(start))
+		}()
+		if t.slowHandlerThreshold > 0 {
+			watchdog := time.AfterFunc(t.slowHandlerThreshold, func() {
+				t.rwMutex.RLock()
+				queueLength := t.eventQueue.Len()
+				t.rwMutex.RUnlock()
+				t.logger.Error(nil, "ResourceSlice event handler is slow, delaying other event handlers", "event", eventType, "threshold", t.slowHandlerThreshold, "pendingEvents", queueLength)
 			})
+			defer watchdog.Stop()
 		}
+		deliver()
 	}
 }
 
@@ -425,6 +519,7 @@ func (t *Tracker) resourceSliceAdd(ctx context.Context) func(obj any) {
 		} else {
 			logger.V(5).Info("ResourceSlice added", "slice", klog.KObj(slice))
 		}
+		t.updateSliceDevices(nil, slice)
 		t.syncSlice(ctx, slice.Name, true)
 	}
 }
@@ -447,6 +542,7 @@ func (t *Tracker) resourceSliceUpdate(ctx context.Context) func(oldObj, newObj a
 		} else {
 			logger.V(5).Info("ResourceSlice updated", "slice", klog.KObj(newSlice))
 		}
+		t.updateSliceDevices(oldSlice, newSlice)
 		t.syncSlice(ctx, newSlice.Name, true)
 	}
 }
@@ -462,6 +558,7 @@ func (t *Tracker) resourceSliceDelete(ctx context.Context) func(obj any) {
 			return
 		}
 		logger.V(5).Info("ResourceSlice deleted", "slice", klog.KObj(slice))
+		t.updateSliceDevices(slice, nil)
 		t.syncSlice(ctx, slice.Name, true)
 	}
 }
@@ -478,9 +575,7 @@ func (t *Tracker) deviceTaintAdd(ctx context.Context) func(obj any) {
 		} else {
 			logger.V(5).Info("DeviceTaintRule added", "deviceTaintRule", klog.KObj(rule))
 		}
-		for _, sliceName := range t.sliceNamesForPatch(ctx, rule) {
-			t.syncSlice(ctx, sliceName, false)
-		}
+		t.syncDevices(ctx, t.updateRule(ctx, rule.Name, rule))
 	}
 }
 
@@ -501,15 +596,10 @@ func (t *Tracker) deviceTaintUpdate(ctx context.Context) func(oldObj, newObj any
 			logger.V(5).Info("DeviceTaintRule updated", "deviceTaintRule", klog.KObj(newRule))
 		}
 
-		// Slices that matched the old patch may need to be updated, in
+		// Devices that matched the old patch may need to be updated, in
 		// case they no longer match the new patch and need to have the
 		// patch's changes reverted.
-		slicesToSync := sets.New[string]()
-		slicesToSync.Insert(t.sliceNamesForPatch(ctx, oldRule)...)
-		slicesToSync.Insert(t.sliceNamesForPatch(ctx, newRule)...)
-		for _, sliceName := range slicesToSync.UnsortedList() {
-			t.syncSlice(ctx, sliceName, false)
-		}
+		t.syncDevices(ctx, t.updateRule(ctx, newRule.Name, newRule))
 	}
 }
 
@@ -524,9 +614,7 @@ func (t *Tracker) deviceTaintDelete(ctx context.Context) func(obj any) {
 			return
 		}
 		logger.V(5).Info("DeviceTaintRule deleted", "patch", klog.KObj(patch))
-		for _, sliceName := range t.sliceNamesForPatch(ctx, patch) {
-			t.syncSlice(ctx, sliceName, false)
-		}
+		t.syncDevices(ctx, t.updateRule(ctx, patch.Name, nil))
 	}
 }
 
@@ -583,6 +671,22 @@ func (t *Tracker) deviceClassDelete(ctx context.Context) func(obj any) {
 	}
 }
 
+// syncDevices updates all slices which list one of the devices.
+func (t *Tracker) syncDevices(ctx context.Context, deviceIDs sets.Set[string]) {
+	sliceNames := sets.New[string]()
+	for id := range deviceIDs {
+		names, err := t.resourceSlices.GetIndexer().IndexKeys(driverPoolDeviceIndexName, id)
+		if err != nil {
+			t.handleError(ctx, err, "failed listing ResourceSlices for driver/pool/device key", "key", id)
+			continue
+		}
+		sliceNames.Insert(names...)
+	}
+	for _, sliceName := range sets.List(sliceNames) {
+		t.syncSlice(ctx, sliceName, false)
+	}
+}
+
 // syncSlice updates the slice with the given name, applying
 // DeviceTaints that match. sendEvent is used to force the Tracker
 // to publish an event for listeners added by [Tracker.AddEventHandler]. It
@@ -613,6 +717,7 @@ func (t *Tracker) syncSlice(ctx context.Context, name string, sendEvent bool) {
 			return
 		}
 		t.pushEvent(oldPatchedObj, nil)
+		t.forgetPatchedSlice(name)
 		logger.V(5).Info("patched ResourceSlice deleted")
 		return
 	}
@@ -631,12 +736,7 @@ func (t *Tracker) syncSlice(ctx context.Context, name string, sendEvent bool) {
 		return
 	}
 
-	patches := typedSlice[*resourcebetaapi.DeviceTaintRule](t.deviceTaints.GetIndexer().List())
-	patchedSlice, err := t.applyPatches(ctx, slice, patches)
-	if err != nil {
-		t.handleError(ctx, err, "failed to apply patches to ResourceSlice", "resourceslice", klog.KObj(slice))
-		return
-	}
+	patchedSlice := t.applyPatches(ctx, slice)
 
 	// When syncSlice is triggered by something other than a ResourceSlice
 	// event, only the device attributes and capacity might change. We
@@ -666,59 +766,6 @@ func (t *Tracker) syncSlice(ctx context.Context, name string, sendEvent bool) {
 	}
 }
 
-func (t *Tracker) applyPatches(ctx context.Context, slice *resourceapi.ResourceSlice, taintRules []*resourcebetaapi.DeviceTaintRule) (*resourceapi.ResourceSlice, error) {
-	logger := klog.FromContext(ctx)
-
-	// slice will be DeepCopied just-in-time, only when necessary.
-	patchedSlice := slice
-
-	for _, taintRule := range taintRules {
-		logger := klog.LoggerWithValues(logger, "deviceTaintRule", klog.KObj(taintRule))
-		logger.V(6).Info("processing DeviceTaintRule")
-
-		deviceSelector := taintRule.Spec.DeviceSelector
-		var deviceName *string
-		if deviceSelector != nil {
-			if deviceSelector.Driver != nil && *deviceSelector.Driver != slice.Spec.Driver {
-				logger.V(7).Info("DeviceTaintRule does not apply, mismatched driver", "sliceDriver", slice.Spec.Driver, "taintDriver", *deviceSelector.Driver)
-				continue
-			}
-			if deviceSelector.Pool != nil && *deviceSelector.Pool != slice.Spec.Pool.Name {
-				logger.V(7).Info("DeviceTaintRule does not apply, mismatched pool", "slicePool", slice.Spec.Pool.Name, "taintPool", *deviceSelector.Pool)
-				continue
-			}
-			deviceName = deviceSelector.Device
-		}
-		for dIndex, device := range slice.Spec.Devices {
-			deviceID := deviceID(slice.Spec.Driver, slice.Spec.Pool.Name, device.Name)
-			logger := logger.WithValues("device", deviceID)
-
-			if deviceName != nil && *deviceName != device.Name {
-				logger.V(7).Info("DeviceTaintRule does not apply, mismatched device", "sliceDevice", device.Name, "taintDevice", *deviceSelector.Device)
-				continue
-			}
-
-			logger.V(6).Info("applying matching DeviceTaintRule")
-
-			// TODO: remove conversion once taint is already in the right API package.
-			ta := resourceapi.DeviceTaint{
-				Key:       taintRule.Spec.Taint.Key,
-				Value:     taintRule.Spec.Taint.Value,
-				Effect:    resourceapi.DeviceTaintEffect(taintRule.Spec.Taint.Effect),
-				TimeAdded: taintRule.Spec.Taint.TimeAdded,
-			}
-
-			if patchedSlice == slice {
-				patchedSlice = slice.DeepCopy()
-			}
-
-			patchedSlice.Spec.Devices[dIndex].Taints = append(patchedSlice.Spec.Devices[dIndex].Taints, ta)
-		}
-	}
-
-	return patchedSlice, nil
-}
-
 func taintsEqual(a, b resourceapi.DeviceTaint) bool {
 	return a.Key == b.Key &&
 		a.Effect == b.Effect &&
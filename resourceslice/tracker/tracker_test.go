@@ -35,6 +35,7 @@ import (
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/dynamic-resource-allocation/structured/schedulerapi"
 	"k8s.io/klog/v2"
 	"k8s.io/klog/v2/ktesting"
 	_ "k8s.io/klog/v2/ktesting/init"
@@ -619,6 +620,7 @@ func TestListPatchedResourceSlices(t *testing.T) {
 		slices.SortFunc(test.expectedPatchedSlices, sortResourceSlicesFunc)
 		slices.SortFunc(patchedResourceSlices, sortResourceSlicesFunc)
 		assert.Equal(tCtx, test.expectedPatchedSlices, patchedResourceSlices)
+		checkQueries(tCtx, tCtx.Tracker, patchedResourceSlices)
 		expectEvents := test.expectEvents
 		if expectEvents == nil {
 			expectEvents = func(t *assert.CollectT, events *v1.EventList) {
@@ -955,29 +957,11 @@ func BenchmarkEventHandlers(b *testing.B) {
 		},
 	}
 
-	newBenchTracker := func(ctx context.Context) *Tracker {
-		kubeClient := fake.NewSimpleClientset()
-		informerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 10*time.Minute)
-		opts := Options{
-			EnableDeviceTaintRules: true,
-			SliceInformer:          informerFactory.Resource().V1().ResourceSlices(),
-			TaintInformer:          informerFactory.Resource().V1beta2().DeviceTaintRules(),
-			ClassInformer:          informerFactory.Resource().V1().DeviceClasses(),
-			KubeClient:             kubeClient,
-		}
-		tracker, err := newTracker(ctx, opts)
-		require.NoError(b, err)
-		tracker.handleError = func(_ context.Context, err error, _ string, _ ...any) {
-			b.Error("unexpected unhandled error:", err)
-		}
-		return tracker
-	}
-
 	for name, benchmark := range benchmarks {
 		b.Run(name, func(b *testing.B) {
 			logger, ctx := ktesting.NewTestContext(b)
 			ctx = klog.NewContext(ctx, logger.V(2))
-			tracker := newBenchTracker(ctx)
+			tracker := newBenchTracker(ctx, b)
 
 			for _, slice := range benchmark.resourceSlices {
 				err := tracker.resourceSlices.GetIndexer().Add(slice)
@@ -996,3 +980,178 @@ func BenchmarkEventHandlers(b *testing.B) {
 		})
 	}
 }
+
+func TestSlowEventHandler(t *testing.T) {
+	logger := ktesting.NewLogger(t, ktesting.NewConfig(ktesting.BufferLogs(true)))
+	ctx := klog.NewContext(context.Background(), logger)
+	kubeClient := fake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 10*time.Minute)
+	tracker, err := newTracker(ctx, Options{
+		EnableDeviceTaintRules: true,
+		SliceInformer:          informerFactory.Resource().V1().ResourceSlices(),
+		TaintInformer:          informerFactory.Resource().V1beta2().DeviceTaintRules(),
+		ClassInformer:          informerFactory.Resource().V1().DeviceClasses(),
+		SlowHandlerThreshold:   10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer tracker.Stop()
+
+	release := make(chan struct{})
+	_, err = tracker.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			<-release
+		},
+	})
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, tracker.resourceSlices.GetIndexer().Add(slice1))
+		tracker.resourceSliceAdd(ctx)(slice1)
+	}()
+	defer func() {
+		close(release)
+		<-done
+	}()
+
+	// The handler is still blocked when the error gets logged.
+	buffer := logger.GetSink().(ktesting.Underlier).GetBuffer()
+	assert.EventuallyWithT(t, func(t *assert.CollectT) {
+		assert.Contains(t, buffer.String(), "ResourceSlice event handler is slow")
+	}, 10*time.Second, time.Millisecond)
+}
+
+// BenchmarkIncrementalPatch moves a taint from one pool to another.
+// Only the slices of those two pools need to be patched again.
+func BenchmarkIncrementalPatch(b *testing.B) {
+	logger, ctx := ktesting.NewTestContext(b)
+	ctx = klog.NewContext(ctx, logger.V(2))
+	tracker := newBenchTracker(ctx, b)
+
+	const numPools = 500
+	for i := range numPools {
+		slice := &resourceapi.ResourceSlice{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "slice-" + strconv.Itoa(i),
+			},
+			Spec: resourceapi.ResourceSliceSpec{
+				Driver: driver1,
+				Pool:   resourceapi.ResourcePool{Name: "pool-" + strconv.Itoa(i)},
+			},
+		}
+		for j := range 64 {
+			slice.Spec.Devices = append(slice.Spec.Devices, resourceapi.Device{Name: "device-" + strconv.Itoa(j)})
+		}
+		err := tracker.resourceSlices.GetIndexer().Add(slice)
+		require.NoError(b, err)
+		tracker.resourceSliceAdd(ctx)(slice)
+	}
+
+	taintRules := make([]*resourcebetaapi.DeviceTaintRule, numPools)
+	for i := range taintRules {
+		taintRules[i] = &resourcebetaapi.DeviceTaintRule{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "taintRule",
+			},
+			Spec: resourcebetaapi.DeviceTaintRuleSpec{
+				DeviceSelector: &resourcebetaapi.DeviceTaintSelector{
+					Driver: &driver1,
+					Pool:   ptr.To("pool-" + strconv.Itoa(i)),
+				},
+				Taint: resourcebetaapi.DeviceTaint{
+					Key:       "example.com/taint",
+					Value:     "tainted",
+					Effect:    resourcebetaapi.DeviceTaintEffectNoExecute,
+					TimeAdded: &metav1.Time{Time: time.Now()},
+				},
+			},
+		}
+	}
+	err := tracker.deviceTaints.GetIndexer().Add(taintRules[0])
+	require.NoError(b, err)
+	tracker.deviceTaintAdd(ctx)(taintRules[0])
+
+	b.ResetTimer()
+	for i := range b.N {
+		oldRule := taintRules[i%numPools]
+		newRule := taintRules[(i+1)%numPools]
+		err := tracker.deviceTaints.GetIndexer().Update(newRule)
+		require.NoError(b, err)
+		tracker.deviceTaintUpdate(ctx)(oldRule, newRule)
+	}
+}
+
+func newBenchTracker(ctx context.Context, b *testing.B) *Tracker {
+	kubeClient := fake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 10*time.Minute)
+	opts := Options{
+		EnableDeviceTaintRules: true,
+		SliceInformer:          informerFactory.Resource().V1().ResourceSlices(),
+		TaintInformer:          informerFactory.Resource().V1beta2().DeviceTaintRules(),
+		ClassInformer:          informerFactory.Resource().V1().DeviceClasses(),
+		KubeClient:             kubeClient,
+	}
+	tracker, err := newTracker(ctx, opts)
+	require.NoError(b, err)
+	tracker.handleError = func(_ context.Context, err error, _ string, _ ...any) {
+		b.Error("unexpected unhandled error:", err)
+	}
+	return tracker
+}
+
+// checkQueries verifies that the indexed lookups are consistent with
+// the complete list of patched ResourceSlices.
+func checkQueries(t *testContext, tracker *Tracker, patchedResourceSlices []*resourceapi.ResourceSlice) {
+	for _, slice := range patchedResourceSlices {
+		driverSlices, err := tracker.ListPatchedResourceSlicesForDriver(slice.Spec.Driver)
+		require.NoError(t, err, "list by driver")
+		assert.Contains(t, driverSlices, slice, "list by driver")
+
+		poolSlices, err := tracker.ListPatchedResourceSlicesForPool(slice.Spec.Driver, slice.Spec.Pool.Name)
+		require.NoError(t, err, "list by pool")
+		assert.Contains(t, poolSlices, slice, "list by pool")
+
+		if slice.Spec.NodeName != nil {
+			nodeSlices, err := tracker.ListPatchedResourceSlicesForNode(*slice.Spec.NodeName)
+			require.NoError(t, err, "list by node")
+			assert.Contains(t, nodeSlices, slice, "list by node")
+		}
+
+		for i := range slice.Spec.Devices {
+			device, err := tracker.GetPatchedDevice(schedulerapi.MakeDeviceID(slice.Spec.Driver, slice.Spec.Pool.Name, slice.Spec.Devices[i].Name))
+			require.NoError(t, err, "get device")
+			assert.Equal(t, &slice.Spec.Devices[i], device, "get device")
+		}
+	}
+	device, err := tracker.GetPatchedDevice(schedulerapi.MakeDeviceID("no-such-driver", "pool", "device"))
+	require.NoError(t, err, "get unknown device")
+	assert.Nil(t, device, "get unknown device")
+}
+
+func TestSharedSliceInformer(t *testing.T) {
+	_, ctx := ktesting.NewTestContext(t)
+	kubeClient := fake.NewSimpleClientset()
+	informerFactory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 10*time.Minute)
+	sliceInformer := informerFactory.Resource().V1().ResourceSlices()
+
+	// The caller's own indexer is not affected by the tracker.
+	require.NoError(t, sliceInformer.Informer().AddIndexers(cache.Indexers{
+		"driver": func(obj any) ([]string, error) { return nil, nil },
+	}))
+
+	// Several trackers may share the same informer.
+	for i := range 2 {
+		tracker, err := StartTracker(ctx, Options{SliceInformer: sliceInformer})
+		require.NoError(t, err, "tracker #%d", i)
+		tracker.Stop()
+	}
+
+	// A different indexer with the same name is a conflict.
+	conflictInformer := informers.NewSharedInformerFactoryWithOptions(kubeClient, 10*time.Minute).Resource().V1().ResourceSlices()
+	require.NoError(t, conflictInformer.Informer().AddIndexers(cache.Indexers{
+		driverIndexName: func(obj any) ([]string, error) { return nil, nil },
+	}))
+	_, err := StartTracker(ctx, Options{SliceInformer: conflictInformer})
+	require.ErrorContains(t, err, `indexer "dra-tracker-driver" exists already with a different function`)
+}
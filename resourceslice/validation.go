@@ -17,10 +17,9 @@ limitations under the License.
 package resourceslice
 
 import (
-	"fmt"
-
 	resourceapi "k8s.io/api/resource/v1"
-	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/dynamic-resource-allocation/internal/poolvalidation"
 )
 
 // validateDriverResources identifies problems that cannot be caught by
@@ -28,9 +27,9 @@ import (
 // ResourceSlices, like incorrect cross-references. We do validation here
 // so any issues can be discovered as early as possible. This is also
 // checked in the allocator before allocating any devices.
-func validateDriverResources(resources *DriverResources) error {
+func validateDriverResources(driverName string, resources *DriverResources) error {
 	for poolName, pool := range resources.Pools {
-		if err := validatePool(poolName, pool); err != nil {
+		if err := validatePool(driverName, poolName, pool); err != nil {
 			return err
 		}
 	}
@@ -39,41 +38,20 @@ func validateDriverResources(resources *DriverResources) error {
 
 // validatePool checks that there aren't any pool-wide issues that
 // can't be caught in the API-server per-ResourceSlice validation.
-//
-// This logic is very similar to what we do in the allocator when we
-// gather the pools. We might want to see if there is a good way to
-// put this logic in one place.
-func validatePool(name string, pool Pool) error {
-	counterSets := make(map[string]resourceapi.CounterSet)
-	for _, slice := range pool.Slices {
-		for _, counterSet := range slice.SharedCounters {
-			if _, found := counterSets[counterSet.Name]; found {
-				return fmt.Errorf("found duplicate counter set %q in pool %q", counterSet.Name, name)
-			}
-			counterSets[counterSet.Name] = counterSet
-		}
+// The same checks are done by the allocator when it gathers the pools.
+func validatePool(driverName, name string, pool Pool) error {
+	slicesPath := field.NewPath("pools").Key(name).Child("slices")
+	slices := make([]poolvalidation.Slice, 0, len(pool.Slices))
+	for i, slice := range pool.Slices {
+		slices = append(slices, poolvalidation.Slice{
+			Path: slicesPath.Index(i),
+			Spec: &resourceapi.ResourceSliceSpec{
+				Driver:                 driverName,
+				Devices:                slice.Devices,
+				SharedCounters:         slice.SharedCounters,
+				PerDeviceNodeSelection: slice.PerDeviceNodeSelection,
+			},
+		})
 	}
-
-	devices := sets.New[string]()
-	for _, slice := range pool.Slices {
-		for _, device := range slice.Devices {
-			if _, found := devices[device.Name]; found {
-				return fmt.Errorf("found duplicate device %q in pool %q", device.Name, name)
-			}
-			devices.Insert(device.Name)
-
-			for _, dcc := range device.ConsumesCounters {
-				counterSet, found := counterSets[dcc.CounterSet]
-				if !found {
-					return fmt.Errorf("counter set %q referenced by device %q not found", dcc.CounterSet, device.Name)
-				}
-				for counterName := range dcc.Counters {
-					if _, found := counterSet.Counters[counterName]; !found {
-						return fmt.Errorf("counter %q referenced by device %q not found in counter set %q", counterName, device.Name, counterSet.Name)
-					}
-				}
-			}
-		}
-	}
-	return nil
+	return poolvalidation.ValidatePool(slices, poolvalidation.Options{}).ToAggregate()
 }
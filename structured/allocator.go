@@ -27,6 +27,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/dynamic-resource-allocation/cel"
+	"k8s.io/dynamic-resource-allocation/deviceclass/selectorregistry"
 	"k8s.io/dynamic-resource-allocation/structured/internal"
 	"k8s.io/dynamic-resource-allocation/structured/internal/experimental"
 	"k8s.io/dynamic-resource-allocation/structured/internal/incubating"
@@ -48,6 +49,10 @@ import (
 // It has no text of its own and can be used with fmt.Errorf("%wsome other error", ErrFailedAllocationOnNode).
 var ErrFailedAllocationOnNode = internal.ErrFailedAllocationOnNode
 
+// ErrCELCostBudgetExceeded is wrapped by errors returned by Allocate when
+// the CEL selectors for a claim exceeded [CELCostBudget.PerClaim].
+var ErrCELCostBudgetExceeded = internal.ErrCELCostBudgetExceeded
+
 // To keep the code in different packages simple, type aliases are used everywhere.
 // Functions are wrappers instead of variables to enable compiler optimization.
 // The Allocator interface is defined twice intentionally: that way, the docs
@@ -55,6 +60,8 @@ var ErrFailedAllocationOnNode = internal.ErrFailedAllocationOnNode
 
 type DeviceClassLister = internal.DeviceClassLister
 type Features = internal.Features
+type CELCostBudget = internal.CELCostBudget
+type CELCostReport = internal.CELCostReport
 
 // Type aliases to schedulerapi package for types that are part of the
 // scheduler and autoscaler contract. This ensures that changes to these
@@ -120,6 +127,48 @@ type Allocator interface {
 	Allocate(ctx context.Context, node *v1.Node, claims []*resourceapi.ResourceClaim) (finalResult []resourceapi.AllocationResult, finalErr error)
 }
 
+// AllocatorWithCELCostBudget is implemented by allocators which support
+// tracking the actual runtime cost of CEL selectors. Callers can check
+// for it with a type assertion on the result of NewAllocator. Not all
+// implementations support it, currently only the one which gets
+// picked when alpha features are enabled does.
+type AllocatorWithCELCostBudget interface {
+	Allocator
+
+	// SetCELCostBudget configures a per-claim limit for the cost of CEL
+	// selectors and/or a callback which receives the cost per claim
+	// and per DeviceClass after each Allocate call.
+	//
+	// Must be called before the first Allocate call.
+	SetCELCostBudget(budget CELCostBudget)
+}
+
+// AllocatorWithCELErrorReporter is implemented by allocators which can
+// record CEL runtime errors in a [cel.RuntimeErrorReporter]. The reporter
+// can be shared with other components and provides deduplicated,
+// rate-limited errors for logging, Events and metrics.
+//
+// Allocate still returns those errors as before.
+type AllocatorWithCELErrorReporter interface {
+	Allocator
+
+	// SetCELErrorReporter must be called before the first Allocate call.
+	SetCELErrorReporter(reporter *cel.RuntimeErrorReporter)
+}
+
+// AllocatorWithSelectorRegistry is implemented by allocators which can use
+// the DeviceClass selectors compiled by a [selectorregistry.Registry]
+// instead of looking them up in the CEL cache. Selectors of a class which
+// is unknown to the registry or known in a different version get compiled
+// as before. The same applies to all classes when the registry uses
+// different CEL features than the allocator's cache.
+type AllocatorWithSelectorRegistry interface {
+	Allocator
+
+	// SetSelectorRegistry must be called before the first Allocate call.
+	SetSelectorRegistry(registry *selectorregistry.Registry)
+}
+
 // NewAllocator returns an allocator for a certain set of claims or an error if
 // some problem was detected which makes it impossible to allocate claims.
 //
@@ -40,6 +40,7 @@ import (
 	apitypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/dynamic-resource-allocation/cel"
+	"k8s.io/dynamic-resource-allocation/deviceclass/selectorregistry"
 	"k8s.io/dynamic-resource-allocation/structured/internal"
 	"k8s.io/klog/v2/ktesting"
 	"k8s.io/utils/ptr"
@@ -160,6 +161,25 @@ func class(name, driver string) *resourceapi.DeviceClass {
 	}
 }
 
+// generate a DeviceClass object with the given name, resource version and CEL selector.
+func classWithVersion(name, resourceVersion, expression string) *resourceapi.DeviceClass {
+	return &resourceapi.DeviceClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			ResourceVersion: resourceVersion,
+		},
+		Spec: resourceapi.DeviceClassSpec{
+			Selectors: []resourceapi.DeviceSelector{
+				{
+					CEL: &resourceapi.CELDeviceSelector{
+						Expression: expression,
+					},
+				},
+			},
+		},
+	}
+}
+
 // generate a DeviceClass object with the given name and a driver CEL selector.
 // driver name is assumed to be the same as the class name.
 // shared condition is explicitly set.
@@ -905,6 +925,29 @@ type AllocatorTestCase struct {
 	slices                   []*resourceapi.ResourceSlice
 	node                     *v1.Node
 
+	// deviceSchedulingFields enables device.taints, device.bindingConditions
+	// and device.consumesCounters in CEL expressions.
+	deviceSchedulingFields bool
+
+	// celCostBudget gets passed to allocators which implement
+	// internal.AllocatorWithCELCostBudget, other allocators skip the test case.
+	// Its Report callback gets replaced. The reports of the Allocate call
+	// must match expectCELCostReports.
+	celCostBudget        *internal.CELCostBudget
+	expectCELCostReports types.GomegaMatcher
+
+	// expectCELRuntimeErrors enables a cel.RuntimeErrorReporter in allocators
+	// which implement internal.AllocatorWithCELErrorReporter, other allocators
+	// skip the test case. The errors recorded by it must match.
+	expectCELRuntimeErrors types.GomegaMatcher
+
+	// registryClasses enables a selectorregistry.Registry which has seen
+	// those classes in allocators which implement
+	// internal.AllocatorWithSelectorRegistry, other allocators skip the
+	// test case. The registry compiles with registryCELFeatures.
+	registryClasses     []*resourceapi.DeviceClass
+	registryCELFeatures cel.Features
+
 	expectResults []any
 	expectError   types.GomegaMatcher // can be used to check for no error or match specific error
 
@@ -2334,6 +2377,99 @@ func TestAllocator(t *testing.T,
 
 			expectError: gomega.MatchError(gomega.ContainSubstring("undeclared reference")),
 		},
+		"cel-cost-budget-unlimited": {
+			claimsToAllocate: objects(claimWithRequest(claim0, req0, classA)),
+			classes:          objects(class(classA, driverA)),
+			slices:           unwrapResourceSlices(sliceWithOneDevice(slice1, node1, pool1, driverA)),
+			node:             node(node1, region1),
+			celCostBudget:    &internal.CELCostBudget{},
+
+			expectResults: []any{allocationResult(
+				localNodeSelector(node1),
+				deviceAllocationResult(req0, driverA, pool1, device1, false),
+			)},
+			expectCELCostReports: gomega.HaveExactElements(celCostOfClassSelector(classA)),
+		},
+		"cel-cost-budget-sufficient": {
+			claimsToAllocate: objects(claimWithRequest(claim0, req0, classA)),
+			classes:          objects(class(classA, driverA)),
+			slices:           unwrapResourceSlices(sliceWithOneDevice(slice1, node1, pool1, driverA)),
+			node:             node(node1, region1),
+			celCostBudget:    &internal.CELCostBudget{PerClaim: 1000},
+
+			expectResults: []any{allocationResult(
+				localNodeSelector(node1),
+				deviceAllocationResult(req0, driverA, pool1, device1, false),
+			)},
+			expectCELCostReports: gomega.HaveExactElements(celCostOfClassSelector(classA)),
+		},
+		"cel-cost-budget-exceeded": {
+			claimsToAllocate: objects(claimWithRequest(claim0, req0, classA)),
+			classes:          objects(class(classA, driverA)),
+			slices:           unwrapResourceSlices(sliceWithOneDevice(slice1, node1, pool1, driverA)),
+			node:             node(node1, region1),
+			celCostBudget:    &internal.CELCostBudget{PerClaim: 1},
+
+			expectError: gomega.And(
+				gomega.MatchError(internal.ErrCELCostBudgetExceeded),
+				gomega.MatchError(gomega.ContainSubstring("claim claim-0: CEL selectors used a cost of")),
+			),
+			expectCELCostReports: gomega.HaveExactElements(celCostOfClassSelector(classA)),
+		},
+		"cel-runtime-error-reported": {
+			claimsToAllocate: objects(claimWithRequest(claim0, req0, classA)),
+			classes: objects(
+				func() *resourceapi.DeviceClass {
+					c := class(classA, driverA)
+					c.Spec.Selectors[0].CEL.Expression = `device.attributes["` + driverA + `"].noSuchAttr`
+					return c
+				}(),
+			),
+			slices: unwrapResourceSlices(sliceWithOneDevice(slice1, node1, pool1, driverA)),
+			node:   node(node1, region1),
+
+			// Errors are still returned, in addition to being recorded.
+			expectError: gomega.MatchError(gomega.ContainSubstring("CEL runtime error")),
+			expectCELRuntimeErrors: gomega.HaveExactElements(gomega.And(
+				gomega.HaveField("Expression", `device.attributes["`+driverA+`"].noSuchAttr`),
+				gomega.HaveField("Kind", cel.RuntimeErrorNoSuchKey),
+				gomega.HaveField("Total", int64(1)),
+			)),
+		},
+		// The classes seen by the lister in the selector-registry test cases
+		// have an invalid expression. Allocation only succeeds when the
+		// allocator uses the registry's results for the same class version.
+		"selector-registry-precompiled": {
+			claimsToAllocate: objects(claimWithRequest(claim0, req0, classA)),
+			classes:          objects(classWithVersion(classA, "1", "device.noSuchField")),
+			slices:           unwrapResourceSlices(sliceWithOneDevice(slice1, node1, pool1, driverA)),
+			node:             node(node1, region1),
+			registryClasses:  objects(classWithVersion(classA, "1", fmt.Sprintf(`device.driver == "%s"`, driverA))),
+
+			expectResults: []any{allocationResult(
+				localNodeSelector(node1),
+				deviceAllocationResult(req0, driverA, pool1, device1, false),
+			)},
+		},
+		"selector-registry-different-version": {
+			claimsToAllocate: objects(claimWithRequest(claim0, req0, classA)),
+			classes:          objects(classWithVersion(classA, "2", "device.noSuchField")),
+			slices:           unwrapResourceSlices(sliceWithOneDevice(slice1, node1, pool1, driverA)),
+			node:             node(node1, region1),
+			registryClasses:  objects(classWithVersion(classA, "1", fmt.Sprintf(`device.driver == "%s"`, driverA))),
+
+			expectError: gomega.MatchError(gomega.ContainSubstring("CEL compile error")),
+		},
+		"selector-registry-different-features": {
+			claimsToAllocate:    objects(claimWithRequest(claim0, req0, classA)),
+			classes:             objects(classWithVersion(classA, "1", "device.noSuchField")),
+			slices:              unwrapResourceSlices(sliceWithOneDevice(slice1, node1, pool1, driverA)),
+			node:                node(node1, region1),
+			registryClasses:     objects(classWithVersion(classA, "1", fmt.Sprintf(`device.driver == "%s"`, driverA))),
+			registryCELFeatures: cel.Features{EnableDeviceSchedulingFields: true},
+
+			expectError: gomega.MatchError(gomega.ContainSubstring("CEL compile error")),
+		},
 		"too-many-devices-single-request": {
 			claimsToAllocate: objects(claimWithRequests(claim0, nil, request(req0, classA, 500))),
 			classes:          objects(class(classA, driverA)),
@@ -3838,6 +3974,25 @@ func TestAllocator(t *testing.T,
 				deviceAllocationResult(req0, driverA, pool1, device1, false),
 			)},
 		},
+		"tainted-selected-by-cel": {
+			deviceSchedulingFields: true,
+			claimsToAllocate: objects(claimWithRequests(claim0, nil,
+				request(req0, classA, 1, resourceapi.DeviceSelector{
+					CEL: &resourceapi.CELDeviceSelector{
+						Expression: `size(device.taints) == 0`,
+					}}),
+			)),
+			classes: objects(class(classA, driverA)),
+			slices: unwrapResourceSlices(sliceWithDevices(slice1, node1, pool1, driverA,
+				device(device1, nil, nil).withTaints(taintNone),
+				device(device2, nil, nil),
+			)),
+			node: node(node1, region1),
+			expectResults: []any{allocationResult(
+				localNodeSelector(node1),
+				deviceAllocationResult(req0, driverA, pool1, device2, false),
+			)},
+		},
 		"tainted-prioritized-list": {
 			features: Features{
 				DeviceTaints:    true,
@@ -7036,6 +7191,65 @@ func TestAllocator(t *testing.T,
 			node:        node(node1, region1),
 			expectError: gomega.MatchError(gomega.ContainSubstring("invalid resource pools were encountered")),
 		},
+		"device-consuming-more-than-available-does-not-invalidate-pool": {
+			features: Features{
+				PartitionableDevices: true,
+			},
+			claimsToAllocate: objects(
+				claimWithRequests(claim0, nil,
+					request(req0, classA, 1),
+				),
+			),
+			classes: objects(class(classA, driverA)),
+			slices: unwrapResourceSlices(
+				sliceWithCounterSets(slice1, node1, resourcePool(pool1, 2), driverA,
+					counterSet(counterSet1, map[string]resource.Quantity{
+						"memory": resource.MustParse("8Gi"),
+					}),
+				),
+				sliceWithDevices(slice2, node1, resourcePool(pool1, 2), driverA,
+					device(device1, nil, nil).withDeviceCounterConsumption(
+						deviceCounterConsumption(counterSet1, map[string]resource.Quantity{
+							"memory": resource.MustParse("16Gi"),
+						}),
+					),
+					device(device2, nil, nil).withDeviceCounterConsumption(
+						deviceCounterConsumption(counterSet1, map[string]resource.Quantity{
+							"memory": resource.MustParse("4Gi"),
+						}),
+					),
+				),
+			),
+			node: node(node1, region1),
+			expectResults: []any{allocationResult(
+				localNodeSelector(node1),
+				deviceAllocationResult(req0, driverA, pool1, device2, false),
+			)},
+		},
+		"attributes-with-different-types-do-not-invalidate-pool": {
+			claimsToAllocate: objects(
+				claimWithRequests(claim0, nil,
+					request(req0, classA, 2),
+				),
+			),
+			classes: objects(class(classA, driverA)),
+			slices: unwrapResourceSlices(
+				sliceWithDevices(slice1, node1, pool1, driverA,
+					device(device1, nil, map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						"model": {StringValue: ptr.To("a100")},
+					}),
+					device(device2, nil, map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+						"model": {IntValue: ptr.To(int64(100))},
+					}),
+				),
+			),
+			node: node(node1, region1),
+			expectResults: []any{allocationResult(
+				localNodeSelector(node1),
+				deviceAllocationResult(req0, driverA, pool1, device1, false),
+				deviceAllocationResult(req0, driverA, pool1, device2, false),
+			)},
+		},
 		"no-allocation-from-incomplete-pools": {
 			claimsToAllocate: objects(
 				claimWithRequests(claim0, nil,
@@ -7372,7 +7586,7 @@ func RunTestAllocator(t *testing.T,
 	testcases map[string]AllocatorTestCase) {
 	for name, tc := range testcases {
 		t.Run(name, func(t *testing.T) {
-			_, ctx := ktesting.NewTestContext(t)
+			logger, ctx := ktesting.NewTestContext(t)
 			g := gomega.NewWithT(t)
 
 			required := tc.features.Set()
@@ -7401,23 +7615,64 @@ func RunTestAllocator(t *testing.T,
 				AggregatedCapacity:       allocatedShare,
 			}
 			allocator, err := newAllocator(ctx, tc.features, allocatedState, classLister, slices, cel.NewCache(1, cel.Features{
-				EnableConsumableCapacity: tc.features.ConsumableCapacity,
-				EnableListTypeAttributes: tc.features.ListTypeAttributes,
+				EnableConsumableCapacity:     tc.features.ConsumableCapacity,
+				EnableListTypeAttributes:     tc.features.ListTypeAttributes,
+				EnableDeviceSchedulingFields: tc.deviceSchedulingFields,
 			}))
 			g.Expect(err).ToNot(gomega.HaveOccurred())
 
 			if _, ok := allocator.(internal.AllocatorExtended); tc.expectNumAllocateOneInvocations > 0 && !ok {
 				t.Skipf("%T does not support the AllocatorStats interface", allocator)
 			}
+			claims := unwrap(claimsToAllocate...)
+			var costReports []internal.CELCostReport
+			if tc.celCostBudget != nil {
+				budgetAllocator, ok := allocator.(internal.AllocatorWithCELCostBudget)
+				if !ok {
+					t.Skipf("%T does not support the AllocatorWithCELCostBudget interface", allocator)
+				}
+				budget := *tc.celCostBudget
+				budget.Report = func(reportedClaims []*resourceapi.ResourceClaim, report internal.CELCostReport) {
+					g.Expect(reportedClaims).To(gomega.HaveExactElements(claims))
+					costReports = append(costReports, report)
+				}
+				budgetAllocator.SetCELCostBudget(budget)
+			}
+			if tc.registryClasses != nil {
+				registryAllocator, ok := allocator.(internal.AllocatorWithSelectorRegistry)
+				if !ok {
+					t.Skipf("%T does not support the AllocatorWithSelectorRegistry interface", allocator)
+				}
+				registry := selectorregistry.NewRegistry(logger, cel.NewCache(1, tc.registryCELFeatures), nil)
+				for _, class := range tc.registryClasses {
+					registry.OnAdd(class, true)
+				}
+				registryAllocator.SetSelectorRegistry(registry)
+			}
+			var errorReporter *cel.RuntimeErrorReporter
+			if tc.expectCELRuntimeErrors != nil {
+				reporterAllocator, ok := allocator.(internal.AllocatorWithCELErrorReporter)
+				if !ok {
+					t.Skipf("%T does not support the AllocatorWithCELErrorReporter interface", allocator)
+				}
+				errorReporter = cel.NewRuntimeErrorReporter(time.Hour)
+				reporterAllocator.SetCELErrorReporter(errorReporter)
+			}
 			if tc.node == nil {
 				tc.node = node(node1, region1)
 			}
-			results, err := allocator.Allocate(ctx, tc.node, unwrap(claimsToAllocate...))
+			results, err := allocator.Allocate(ctx, tc.node, claims)
 			matchError := tc.expectError
 			if matchError == nil {
 				matchError = gomega.Not(gomega.HaveOccurred())
 			}
 			g.Expect(err).To(matchError)
+			if tc.celCostBudget != nil {
+				g.Expect(costReports).To(tc.expectCELCostReports, "one report per Allocate call")
+			}
+			if tc.expectCELRuntimeErrors != nil {
+				g.Expect(errorReporter.Counts()).To(tc.expectCELRuntimeErrors)
+			}
 
 			t.Logf("name: %s", name)
 			// replace any share id with fixed value for testing
@@ -7448,6 +7703,15 @@ func RunTestAllocator(t *testing.T,
 	}
 }
 
+// celCostOfClassSelector matches the [internal.CELCostReport] for a single claim
+// where the selector of the class is the only selector.
+func celCostOfClassSelector(className string) types.GomegaMatcher {
+	return gomega.And(
+		gomega.HaveField("ClaimCosts", gomega.HaveExactElements(gomega.BeNumerically(">", 0))),
+		gomega.HaveField("ClassCosts", gomega.HaveKeyWithValue(className, gomega.BeNumerically(">", 0))),
+	)
+}
+
 type informerLister[T any] struct {
 	objs []*T
 	err  error
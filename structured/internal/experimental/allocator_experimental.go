@@ -33,6 +33,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	draapi "k8s.io/dynamic-resource-allocation/api"
 	"k8s.io/dynamic-resource-allocation/cel"
+	"k8s.io/dynamic-resource-allocation/deviceclass/selectorregistry"
 	"k8s.io/dynamic-resource-allocation/resourceclaim"
 	"k8s.io/dynamic-resource-allocation/structured/internal"
 	"k8s.io/klog/v2"
@@ -43,6 +44,8 @@ type DeviceClassLister = internal.DeviceClassLister
 type Features = internal.Features
 type DeviceID = internal.DeviceID
 type Stats = internal.Stats
+type CELCostBudget = internal.CELCostBudget
+type CELCostReport = internal.CELCostReport
 
 func MakeDeviceID(driver, pool, device string) DeviceID {
 	return internal.MakeDeviceID(driver, pool, device)
@@ -108,9 +111,18 @@ type Allocator struct {
 	// amount of work the allocator had to do to allocate devices
 	// for the claims.
 	numAllocateOneInvocations atomic.Int64
+	// celCostBudget is set by SetCELCostBudget and read-only afterwards.
+	celCostBudget CELCostBudget
+	// celErrorReporter is set by SetCELErrorReporter and may be nil.
+	celErrorReporter *cel.RuntimeErrorReporter
+	// selectorRegistry is set by SetSelectorRegistry and may be nil.
+	selectorRegistry *selectorregistry.Registry
 }
 
 var _ internal.AllocatorExtended = &Allocator{}
+var _ internal.AllocatorWithCELCostBudget = &Allocator{}
+var _ internal.AllocatorWithCELErrorReporter = &Allocator{}
+var _ internal.AllocatorWithSelectorRegistry = &Allocator{}
 
 // NewAllocator returns an allocator for a certain set of claims or an error if
 // some problem was detected which makes it impossible to allocate claims.
@@ -162,11 +174,16 @@ func (a *Allocator) Allocate(ctx context.Context, node *v1.Node, claims []*resou
 		requestData:          make(map[requestIndices]requestData),
 		result:               make([]internalAllocationResult, len(claims)),
 		allocatingCapacity:   NewConsumedCapacityCollection(),
+		claimCELCosts:        make([]uint64, len(claims)),
+		classCELCosts:        make(map[string]uint64),
 	}
 	slicesForNode := slices.Concat(alloc.slicesOnNode[node.Name], alloc.slicesShared)
 	alloc.logger.V(5).Info("Starting allocation", "numClaims", len(alloc.claimsToAllocate), "numSlicesForNode", len(slicesForNode))
 	defer func() {
-		alloc.logger.V(5).Info("Done with allocation", "success", len(finalResult) == len(alloc.claimsToAllocate), "err", finalErr)
+		alloc.logger.V(5).Info("Done with allocation", "success", len(finalResult) == len(alloc.claimsToAllocate), "err", finalErr, "celCostPerClaim", alloc.claimCELCosts)
+		if a.celCostBudget.Report != nil {
+			a.celCostBudget.Report(claims, CELCostReport{ClaimCosts: alloc.claimCELCosts, ClassCosts: alloc.classCELCosts})
+		}
 	}()
 
 	// First determine all eligible pools.
@@ -533,6 +550,43 @@ func (a *Allocator) GetStats() Stats {
 	return s
 }
 
+// SetCELCostBudget enables tracking of the actual CEL runtime cost per claim.
+// Must be called before the first Allocate call.
+func (a *Allocator) SetCELCostBudget(budget CELCostBudget) {
+	a.celCostBudget = budget
+}
+
+// SetCELErrorReporter enables recording of CEL runtime errors.
+// Must be called before the first Allocate call.
+func (a *Allocator) SetCELErrorReporter(reporter *cel.RuntimeErrorReporter) {
+	a.celErrorReporter = reporter
+}
+
+// SetSelectorRegistry enables using the precompiled DeviceClass selectors.
+// Must be called before the first Allocate call.
+func (a *Allocator) SetSelectorRegistry(registry *selectorregistry.Registry) {
+	a.selectorRegistry = registry
+}
+
+// compiledClassSelectors returns the selectors of the class as compiled
+// by the registry, nil if not available. The registry might have seen
+// a different version of the class than the lister or might compile with
+// different CEL features than the allocator, in which case its results
+// cannot be used.
+func (a *Allocator) compiledClassSelectors(class *resourceapi.DeviceClass) []cel.CompilationResult {
+	if a.selectorRegistry == nil || a.selectorRegistry.Features() != a.celCache.Features() {
+		return nil
+	}
+	selectors := a.selectorRegistry.GetSelectors(class.Name)
+	if selectors == nil ||
+		selectors.Class.UID != class.UID ||
+		selectors.Class.ResourceVersion != class.ResourceVersion ||
+		len(selectors.Results) != len(class.Spec.Selectors) {
+		return nil
+	}
+	return selectors.Results
+}
+
 func (alloc *allocator) validateDeviceRequest(request requestAccessor, parentRequest requestAccessor, requestKey requestIndices, pools []*Pool) (requestData, error) {
 	claim := alloc.claimsToAllocate[requestKey.claimIndex]
 	requestData := requestData{
@@ -562,6 +616,7 @@ func (alloc *allocator) validateDeviceRequest(request requestAccessor, parentReq
 	// Start collecting information about the request.
 	// The class must be set and stored before calling isSelectable.
 	requestData.class = class
+	requestData.classSelectors = alloc.compiledClassSelectors(class)
 
 	switch request.allocationMode() {
 	case resourceapi.DeviceAllocationModeExactCount:
@@ -666,6 +721,12 @@ type allocator struct {
 	// requested by all allocations targeting that device.
 	allocatingCapacity ConsumedCapacityCollection
 	result             []internalAllocationResult
+	// claimCELCosts accumulates the actual cost of CEL selectors,
+	// one entry per claim in claimsToAllocate.
+	claimCELCosts []uint64
+	// classCELCosts accumulates the actual cost of DeviceClass selectors,
+	// indexed by class name.
+	classCELCosts map[string]uint64
 }
 
 // counterSets is a map with the name of counter sets to the counters in
@@ -714,6 +775,10 @@ type requestData struct {
 	class         *resourceapi.DeviceClass
 	numDevices    int
 
+	// classSelectors are the precompiled selectors of the class,
+	// nil if they need to be compiled.
+	classSelectors []cel.CompilationResult
+
 	// selectedSubRequestIndex is set for the entry with requestIndices.subRequestIndex == 0.
 	// It is the index of the subrequest which got picked during allocation.
 	selectedSubRequestIndex int
@@ -1355,7 +1420,7 @@ func (alloc *allocator) isSelectable(r requestIndices, requestData requestData,
 	}
 
 	if requestData.class != nil {
-		match, err := alloc.selectorsMatch(r, device, deviceID, requestData.class, requestData.class.Spec.Selectors)
+		match, err := alloc.selectorsMatch(r, device, deviceID, requestData.class, requestData.class.Spec.Selectors, requestData.classSelectors)
 		if err != nil {
 			return false, err
 		}
@@ -1366,7 +1431,7 @@ func (alloc *allocator) isSelectable(r requestIndices, requestData requestData,
 	}
 
 	request := requestData.request
-	match, err := alloc.selectorsMatch(r, device, deviceID, nil, request.selectors())
+	match, err := alloc.selectorsMatch(r, device, deviceID, nil, request.selectors(), nil)
 	if err != nil {
 		return false, err
 	}
@@ -1405,9 +1470,16 @@ func (alloc *allocator) CmpRequestOverCapacity(request requestAccessor, slice *d
 	return CmpRequestOverCapacity(NewConsumedCapacity(), request.capacities(), allowMultipleAllocations, capacities, allocatingCapacity)
 }
 
-func (alloc *allocator) selectorsMatch(r requestIndices, device *draapi.Device, deviceID DeviceID, class *resourceapi.DeviceClass, selectors []resourceapi.DeviceSelector) (bool, error) {
+// selectorsMatch checks the selectors against the device. Precompiled
+// selectors are used if available, otherwise they get compiled.
+func (alloc *allocator) selectorsMatch(r requestIndices, device *draapi.Device, deviceID DeviceID, class *resourceapi.DeviceClass, selectors []resourceapi.DeviceSelector, compiled []cel.CompilationResult) (bool, error) {
 	for i, selector := range selectors {
-		expr := alloc.celCache.GetOrCompile(selector.CEL.Expression)
+		var expr cel.CompilationResult
+		if compiled != nil {
+			expr = compiled[i]
+		} else {
+			expr = alloc.celCache.GetOrCompile(selector.CEL.Expression)
+		}
 		if expr.Error != nil {
 			// Could happen if some future apiserver accepted some
 			// future expression and then got downgraded. Normally
@@ -1426,14 +1498,22 @@ func (alloc *allocator) selectorsMatch(r requestIndices, device *draapi.Device,
 		if err := draapi.Convert_api_Device_To_v1_Device(device, &d, nil); err != nil {
 			return false, fmt.Errorf("convert Device %s: %w", deviceID, err)
 		}
-		matches, details, err := expr.DeviceMatches(alloc.ctx, cel.Device{Driver: deviceID.Driver.String(), AllowMultipleAllocations: d.AllowMultipleAllocations, Attributes: d.Attributes, Capacity: d.Capacity})
+		matches, details, err := expr.DeviceMatches(alloc.ctx, cel.Device{Driver: deviceID.Driver.String(), AllowMultipleAllocations: d.AllowMultipleAllocations, Attributes: d.Attributes, Capacity: d.Capacity, Taints: d.Taints, BindingConditions: d.BindingConditions, ConsumesCounters: d.ConsumesCounters})
 		if class != nil {
 			alloc.logger.V(7).Info("CEL result", "device", deviceID, "class", klog.KObj(class), "selector", i, "expression", selector.CEL.Expression, "matches", matches, "actualCost", ptr.Deref(details.ActualCost(), 0), "err", err)
 		} else {
 			alloc.logger.V(7).Info("CEL result", "device", deviceID, "claim", klog.KObj(alloc.claimsToAllocate[r.claimIndex]), "selector", i, "expression", selector.CEL.Expression, "actualCost", ptr.Deref(details.ActualCost(), 0), "matches", matches, "err", err)
 		}
+		if budgetErr := alloc.trackCELCost(r, class, ptr.Deref(details.ActualCost(), 0)); budgetErr != nil {
+			return false, budgetErr
+		}
 
 		if err != nil {
+			if alloc.celErrorReporter != nil {
+				if kind, report, suppressed := alloc.celErrorReporter.Record(selector.CEL.Expression, err); report {
+					alloc.logger.V(4).Info("CEL runtime error", "device", deviceID, "expression", selector.CEL.Expression, "kind", kind, "suppressed", suppressed, "err", err)
+				}
+			}
 			err = cel.EnhanceRuntimeError(err)
 			if class != nil {
 				return false, fmt.Errorf("class %s: selector #%d on device %s: CEL runtime error: %w", class.Name, i, deviceID, err)
@@ -1449,6 +1529,21 @@ func (alloc *allocator) selectorsMatch(r requestIndices, device *draapi.Device,
 	return true, nil
 }
 
+// trackCELCost adds the actual cost of one selector evaluation to the
+// claim and, if the selector came from a class, to that class. It returns
+// an error if the claim exceeded its budget.
+func (alloc *allocator) trackCELCost(r requestIndices, class *resourceapi.DeviceClass, cost uint64) error {
+	alloc.claimCELCosts[r.claimIndex] += cost
+	if class != nil {
+		alloc.classCELCosts[class.Name] += cost
+	}
+	limit := alloc.celCostBudget.PerClaim
+	if limit > 0 && alloc.claimCELCosts[r.claimIndex] > limit {
+		return fmt.Errorf("claim %s: CEL selectors used a cost of %d, more than the limit of %d: %w", klog.KObj(alloc.claimsToAllocate[r.claimIndex]), alloc.claimCELCosts[r.claimIndex], limit, internal.ErrCELCostBudgetExceeded)
+	}
+	return nil
+}
+
 // allocateDevice checks device availability and constraints for one
 // candidate. The device must be selectable.
 //
@@ -27,8 +27,10 @@ import (
 	v1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/component-helpers/scheduling/corev1/nodeaffinity"
 	draapi "k8s.io/dynamic-resource-allocation/api"
+	"k8s.io/dynamic-resource-allocation/internal/poolvalidation"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/ptr"
 )
@@ -57,7 +59,7 @@ func NodeMatches(node *v1.Node, nodeNameToMatch string, allNodesMatch bool, node
 // required slices available) or invalid (for example, device names not unique).
 // Both is recorded in the result.
 func GatherPools(ctx context.Context, slicesForNode []*resourceapi.ResourceSlice, node *v1.Node, features Features, allSlices []*resourceapi.ResourceSlice) ([]*Pool, error) {
-	pools := make(map[PoolID][]*draapi.ResourceSlice)
+	pools := make(map[PoolID]*poolSlices)
 
 	for _, slice := range slicesForNode {
 		if !features.PartitionableDevices && (slice.Spec.PerDeviceNodeSelection != nil || len(slice.Spec.SharedCounters) > 0) {
@@ -116,11 +118,12 @@ func GatherPools(ctx context.Context, slicesForNode []*resourceapi.ResourceSlice
 	// careful with the "is incomplete" check.
 	result := make([]*Pool, 0, len(pools))
 	var resultWithBindingConditions []*Pool
-	for poolID, slicesForPool := range pools {
+	for poolID, poolSlices := range pools {
+		slicesForPool := poolSlices.slices
 		// If we have all slices, we are done.
 		isComplete := int64(len(slicesForPool)) == slicesForPool[0].Spec.Pool.ResourceSliceCount
 		if isComplete {
-			pool, err := buildPool(poolID, slicesForPool, features, nil)
+			pool, err := buildPool(poolID, slicesForPool, poolSlices.originals, features, nil)
 			if err != nil {
 				return nil, err
 			}
@@ -161,7 +164,7 @@ func GatherPools(ctx context.Context, slicesForNode []*resourceapi.ResourceSlice
 			})
 			continue
 		}
-		pool, err := buildPool(poolID, slicesForPool, features, allSlicesForPool)
+		pool, err := buildPool(poolID, slicesForPool, poolSlices.originals, features, allSlicesForPool)
 		if err != nil {
 			return nil, err
 		}
@@ -201,7 +204,14 @@ func sortPoolsByID(pools []*Pool) {
 	})
 }
 
-func addSlice(pools map[PoolID][]*draapi.ResourceSlice, s *resourceapi.ResourceSlice) error {
+// poolSlices contains the slices of one pool with the same generation.
+// The original slices are kept for validation.
+type poolSlices struct {
+	slices    []*draapi.ResourceSlice
+	originals []*resourceapi.ResourceSlice
+}
+
+func addSlice(pools map[PoolID]*poolSlices, s *resourceapi.ResourceSlice) error {
 	var slice draapi.ResourceSlice
 	if err := draapi.Convert_v1_ResourceSlice_To_api_ResourceSlice(s, &slice, nil); err != nil {
 		return fmt.Errorf("convert ResourceSlice: %w", err)
@@ -211,28 +221,31 @@ func addSlice(pools map[PoolID][]*draapi.ResourceSlice, s *resourceapi.ResourceS
 	slicesForPool := pools[id]
 	if slicesForPool == nil {
 		// New pool.
-		pools[id] = []*draapi.ResourceSlice{&slice}
+		pools[id] = &poolSlices{slices: []*draapi.ResourceSlice{&slice}, originals: []*resourceapi.ResourceSlice{s}}
 		return nil
 	}
 
-	if slice.Spec.Pool.Generation < slicesForPool[0].Spec.Pool.Generation {
+	if slice.Spec.Pool.Generation < slicesForPool.slices[0].Spec.Pool.Generation {
 		// Out-dated.
 		return nil
 	}
 
-	if slice.Spec.Pool.Generation > slicesForPool[0].Spec.Pool.Generation {
+	if slice.Spec.Pool.Generation > slicesForPool.slices[0].Spec.Pool.Generation {
 		// Newer, replaces all old slices.
-		pools[id] = []*draapi.ResourceSlice{&slice}
+		pools[id] = &poolSlices{slices: []*draapi.ResourceSlice{&slice}, originals: []*resourceapi.ResourceSlice{s}}
 		return nil
 	}
 
 	// Add to pool.
-	slicesForPool = append(slicesForPool, &slice)
-	pools[id] = slicesForPool
+	slicesForPool.slices = append(slicesForPool.slices, &slice)
+	slicesForPool.originals = append(slicesForPool.originals, s)
 	return nil
 }
 
-func buildPool(id PoolID, slices []*draapi.ResourceSlice, features Features, allSlicesForPool []*resourceapi.ResourceSlice) (*Pool, error) {
+// buildPool creates the pool from the slices which target the node.
+// originals contains the same slices before conversion. allSlicesForPool
+// is non-nil if some slices of the pool do not target the node.
+func buildPool(id PoolID, slices []*draapi.ResourceSlice, originals []*resourceapi.ResourceSlice, features Features, allSlicesForPool []*resourceapi.ResourceSlice) (*Pool, error) {
 	// Sort slices by name to ensure a deterministic allocation order.
 	// Because the allocator uses a first-fit search, this allows driver authors
 	// to influence prioritization through their naming conventions.
@@ -274,7 +287,14 @@ func buildPool(id PoolID, slices []*draapi.ResourceSlice, features Features, all
 		}
 	}
 
-	if err := validateDeviceNames(deviceSlices); err != nil {
+	// If the partitionable devices feature is not enabled, we don't need to
+	// validate counter sets and consumed counters and the slices which
+	// don't target the node.
+	slicesToValidate := originals
+	if features.PartitionableDevices && allSlicesForPool != nil {
+		slicesToValidate = allSlicesForPool
+	}
+	if err := validatePool(slicesToValidate, !features.PartitionableDevices); err != nil {
 		return &Pool{
 			PoolID:        id,
 			IsInvalid:     true,
@@ -282,8 +302,6 @@ func buildPool(id PoolID, slices []*draapi.ResourceSlice, features Features, all
 		}, nil
 	}
 
-	// If the partitionable devices feature is not enabled, we don't need to
-	// validate counter sets and consumed counters, so we are done.
 	if !features.PartitionableDevices {
 		return &Pool{
 			PoolID:                    id,
@@ -291,90 +309,42 @@ func buildPool(id PoolID, slices []*draapi.ResourceSlice, features Features, all
 		}, nil
 	}
 
-	counterSets, err := getAndValidateCounterSets(counterSetSlices)
-	if err != nil {
-		return &Pool{
-			PoolID:        id,
-			IsInvalid:     true,
-			InvalidReason: err.Error(),
-		}, nil
-	}
-
-	// Validate device counter consumption for devices targeting the node.
-	if err := validateDeviceCounterConsumption(counterSets, deviceSlices); err != nil {
-		return &Pool{
-			PoolID:        id,
-			IsInvalid:     true,
-			InvalidReason: err.Error(),
-		}, nil
-	}
-
-	// Validate device counter consumption for devices not targeting the node.
-	if err := validateDeviceCounterConsumption(counterSets, slicesNotTargetingNode); err != nil {
-		return &Pool{
-			PoolID:        id,
-			IsInvalid:     true,
-			InvalidReason: err.Error(),
-		}, nil
-	}
-
 	return &Pool{
 		PoolID:                       id,
 		DeviceSlicesTargetingNode:    deviceSlices,
 		DeviceSlicesNotTargetingNode: slicesNotTargetingNode,
-		CounterSets:                  counterSets,
+		CounterSets:                  getCounterSets(counterSetSlices),
 	}, nil
 }
 
-func getAndValidateCounterSets(slices []*draapi.ResourceSlice) (map[draapi.UniqueString]*draapi.CounterSet, error) {
+// getCounterSets must only be called after validatePool, which ensures
+// that counter set names are unique.
+func getCounterSets(resourceSlices []*draapi.ResourceSlice) map[draapi.UniqueString]*draapi.CounterSet {
 	counterSets := make(map[draapi.UniqueString]*draapi.CounterSet)
-	// We only capture the first error we encounter.
-	for _, slice := range slices {
-		for i := range slice.Spec.SharedCounters {
-			counterSet := slice.Spec.SharedCounters[i]
-			if _, found := counterSets[counterSet.Name]; found {
-				return nil, fmt.Errorf("duplicate counter set name %s", counterSet.Name)
-			}
-			counterSets[counterSet.Name] = &counterSet
-		}
-	}
-	return counterSets, nil
-}
-
-func validateDeviceNames(resourceSlices []*draapi.ResourceSlice) error {
-	deviceNames := sets.New[draapi.UniqueString]()
 	for _, slice := range resourceSlices {
-		for _, device := range slice.Spec.Devices {
-			// Make sure we don't have duplicate device names
-			if deviceNames.Has(device.Name) {
-				return fmt.Errorf("duplicate device name %s", device.Name)
-			}
-			deviceNames.Insert(device.Name)
+		for i := range slice.Spec.SharedCounters {
+			counterSets[slice.Spec.SharedCounters[i].Name] = &slice.Spec.SharedCounters[i]
 		}
 	}
-	return nil
+	return counterSets
 }
 
-func validateDeviceCounterConsumption(counterSets map[draapi.UniqueString]*draapi.CounterSet, resourceSlices []*draapi.ResourceSlice) error {
+// validatePool runs those checks of the ResourceSlice controller which
+// make the entire pool unusable. Mistakes which only affect individual
+// devices are not checked.
+func validatePool(resourceSlices []*resourceapi.ResourceSlice, ignoreCounters bool) error {
+	slicesToValidate := make([]poolvalidation.Slice, 0, len(resourceSlices))
 	for _, slice := range resourceSlices {
-		for _, device := range slice.Spec.Devices {
-			// Make sure all consumed counters for the device references counter sets that exists and
-			// that they consume counters that exists within those counter sets.
-			for _, deviceCounterConsumption := range device.ConsumesCounters {
-				counterSet, found := counterSets[deviceCounterConsumption.CounterSet]
-				if !found {
-					return fmt.Errorf("counter set %s not found", deviceCounterConsumption.CounterSet)
-				}
-
-				for counterName := range deviceCounterConsumption.Counters {
-					if _, found := counterSet.Counters[counterName]; !found {
-						return fmt.Errorf("counter %s not found in counter set %s", counterName, counterSet.Name)
-					}
-				}
-			}
-		}
+		slicesToValidate = append(slicesToValidate, poolvalidation.Slice{
+			Path: field.NewPath("resourceSlices").Key(slice.Name).Child("spec"),
+			Spec: &slice.Spec,
+		})
 	}
-	return nil
+	return poolvalidation.ValidatePool(slicesToValidate, poolvalidation.Options{
+		IgnoreCounters:       ignoreCounters,
+		SkipAPIServerChecks:  true,
+		SkipPublishingChecks: true,
+	}).ToAggregate()
 }
 
 func poolHasBindingConditions(pool Pool) bool {
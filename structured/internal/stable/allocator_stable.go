@@ -1099,7 +1099,7 @@ func (alloc *allocator) selectorsMatch(r requestIndices, device *draapi.Device,
 		if err := draapi.Convert_api_Device_To_v1_Device(device, &d, nil); err != nil {
 			return false, fmt.Errorf("convert Device %s: %w", deviceID, err)
 		}
-		matches, details, err := expr.DeviceMatches(alloc.ctx, cel.Device{Driver: deviceID.Driver.String(), Attributes: d.Attributes, Capacity: d.Capacity})
+		matches, details, err := expr.DeviceMatches(alloc.ctx, cel.Device{Driver: deviceID.Driver.String(), Attributes: d.Attributes, Capacity: d.Capacity, Taints: d.Taints, BindingConditions: d.BindingConditions, ConsumesCounters: d.ConsumesCounters})
 		if class != nil {
 			alloc.logger.V(7).Info("CEL result", "device", deviceID, "class", klog.KObj(class), "selector", i, "expression", selector.CEL.Expression, "matches", matches, "actualCost", ptr.Deref(details.ActualCost(), 0), "err", err)
 		} else {
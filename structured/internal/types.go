@@ -23,12 +23,18 @@ import (
 	v1 "k8s.io/api/core/v1"
 	resourceapi "k8s.io/api/resource/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/dynamic-resource-allocation/cel"
+	"k8s.io/dynamic-resource-allocation/deviceclass/selectorregistry"
 )
 
 // ErrFailedAllocationOnNode is an empty sentinel for errors.Is.
 // See allocator.go for details.
 var ErrFailedAllocationOnNode = errors.New("")
 
+// ErrCELCostBudgetExceeded gets wrapped by errors returned by Allocate when
+// evaluating CEL selectors for a claim exceeded the configured budget.
+var ErrCELCostBudgetExceeded = errors.New("CEL cost budget exceeded")
+
 type DeviceClassLister interface {
 	// List returns a list of all DeviceClasses.
 	List() ([]*resourceapi.DeviceClass, error)
@@ -51,6 +57,53 @@ type AllocatorExtended interface {
 	GetStats() Stats
 }
 
+// AllocatorWithCELCostBudget is an optional interface. Not all variants implement it.
+type AllocatorWithCELCostBudget interface {
+	// SetCELCostBudget configures cost tracking for CEL selectors.
+	// Must be called before the first Allocate call.
+	SetCELCostBudget(budget CELCostBudget)
+}
+
+// AllocatorWithCELErrorReporter is an optional interface. Not all variants implement it.
+type AllocatorWithCELErrorReporter interface {
+	// SetCELErrorReporter configures where CEL runtime errors get recorded.
+	// Must be called before the first Allocate call.
+	SetCELErrorReporter(reporter *cel.RuntimeErrorReporter)
+}
+
+// AllocatorWithSelectorRegistry is an optional interface. Not all variants implement it.
+type AllocatorWithSelectorRegistry interface {
+	// SetSelectorRegistry configures where precompiled DeviceClass selectors come from.
+	// Must be called before the first Allocate call.
+	SetSelectorRegistry(registry *selectorregistry.Registry)
+}
+
+// CELCostBudget limits and reports the actual runtime cost of CEL selectors
+// which gets spent during one Allocate call.
+//
+// The cost of evaluating a DeviceClass selector is attributed to the claim
+// which references the class as well as to the class itself.
+type CELCostBudget struct {
+	// PerClaim is the maximum cost that may be spent on selectors for a
+	// single claim. Zero disables the limit.
+	PerClaim uint64
+
+	// Report gets called at the end of each Allocate call, regardless
+	// of whether it succeeded. Optional.
+	Report func(claims []*resourceapi.ResourceClaim, report CELCostReport)
+}
+
+// CELCostReport contains the actual cost spent during one Allocate call.
+type CELCostReport struct {
+	// ClaimCosts has one entry per claim, in the same order as the claims
+	// that were passed to Allocate.
+	ClaimCosts []uint64
+
+	// ClassCosts contains the cost of DeviceClass selectors, indexed by
+	// the name of the class.
+	ClassCosts map[string]uint64
+}
+
 // Stats shows statistics from the allocation process.
 type Stats struct {
 	// NumAllocateOneInvocations counts the number of times the allocateOne function